@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// authenticatedUserIDSessionKey is the session key holding the id of the
+// logged in user, and redirectAfterLoginSessionKey the key holding the page to
+// return to after logging in.
+const (
+	authenticatedUserIDSessionKey = "authenticatedUserID"
+	redirectAfterLoginSessionKey  = "redirectAfterLogin"
+)
+
+// logIn() records in the session that the user with the given id is logged in.
+// The session token is renewed first, so that a token from before the login
+// (which could have been planted by an attacker) can't be used afterwards.
+func (app *application) logIn(r *http.Request, userID int) error {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	// Issue a fresh CSRF token for the logged in session as well.
+	app.sessionManager.Remove(r.Context(), csrfSessionKey)
+	app.sessionManager.Put(r.Context(), authenticatedUserIDSessionKey, userID)
+	return nil
+}
+
+// logOut() removes the login from the session, again renewing the session and
+// CSRF tokens.
+func (app *application) logOut(r *http.Request) error {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Remove(r.Context(), csrfSessionKey)
+	app.sessionManager.Remove(r.Context(), authenticatedUserIDSessionKey)
+	return nil
+}
+
+// loggedInUserID() returns the id of the user recorded in the session, or zero
+// if nobody is logged in. It doesn't check that the user still exists; the
+// authenticate middleware does that.
+func (app *application) loggedInUserID(r *http.Request) int {
+	return app.sessionManager.GetInt(r.Context(), authenticatedUserIDSessionKey)
+}
+
+// isAuthenticated() returns true if the current request is from a logged in
+// user, as decided by the authenticate middleware.
+func (app *application) isAuthenticated(r *http.Request) bool {
+	isAuthenticated, ok := r.Context().Value(isAuthenticatedContextKey).(bool)
+	if !ok {
+		return false
+	}
+
+	return isAuthenticated
+}
+
+// authenticatedUserID() returns the id of the logged in user making the current
+// request, or zero if there isn't one.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	id, _ := r.Context().Value(authenticatedUserIDContextKey).(int)
+	return id
+}
+
+// isAdmin() returns true if the current request is from a logged in user with
+// the admin role.
+func (app *application) isAdmin(r *http.Request) bool {
+	isAdmin, _ := r.Context().Value(isAdminContextKey).(bool)
+	return isAdmin
+}
+
+// canModify() returns true if the user making the current request may edit or
+// delete the snippet: they must own it, or be an admin. The edit and delete
+// pages fetch the snippet with modifiableSnippetParam(), so that admins get
+// this far for somebody else's private snippet too.
+func (app *application) canModify(r *http.Request, snippet *models.Snippet) bool {
+	return snippet.OwnedBy(app.authenticatedUserID(r)) || app.isAdmin(r)
+}
+
+// redirectAfterLogin() returns the page to send the user to once they have
+// logged in: the page requireAuthentication sent them away from, if any, or
+// else the home page.
+func (app *application) redirectAfterLogin(r *http.Request) string {
+	path := app.sessionManager.PopString(r.Context(), redirectAfterLoginSessionKey)
+
+	// Only ever redirect to a path on this site. Browsers treat "//host" and
+	// "/\host" as URLs on another site.
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+
+	return path
+}
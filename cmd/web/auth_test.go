@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+func TestRequireAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+
+	code, header, _ := ts.get(t, "/snippet/create?from=test")
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d; want %d", code, http.StatusSeeOther)
+	}
+	if location := header.Get("Location"); location != "/user/login" {
+		t.Errorf("got redirect to %q; want %q", location, "/user/login")
+	}
+
+	// After logging in, the user is sent back to the page they wanted.
+	form := url.Values{}
+	form.Add("email", "alice@example.com")
+	form.Add("password", "pa$$word")
+	form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+	_, header, _ = ts.postForm(t, "/user/login", form)
+	if location := header.Get("Location"); location != "/snippet/create?from=test" {
+		t.Errorf("got redirect to %q after logging in; want %q", location, "/snippet/create?from=test")
+	}
+}
+
+func TestRedirectAfterLogin(t *testing.T) {
+	tests := []struct {
+		name  string
+		saved string
+		want  string
+	}{
+		{name: "Nothing saved", saved: "", want: "/"},
+		{name: "Saved page", saved: "/snippet/edit/3", want: "/snippet/edit/3"},
+		{name: "Other site", saved: "//evil.example.com", want: "/"},
+		{name: "Other site with backslash", saved: "/\\evil.example.com", want: "/"},
+		{name: "Absolute URL", saved: "https://evil.example.com/", want: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+
+			var got string
+			h := app.sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.saved != "" {
+					app.sessionManager.Put(r.Context(), redirectAfterLoginSessionKey, tt.saved)
+				}
+				got = app.redirectAfterLogin(r)
+			}))
+
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetCreateOwner(t *testing.T) {
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+	ts.logIn(t, "alice@example.com", "pa$$word")
+
+	form := url.Values{}
+	form.Add("title", "Hello")
+	form.Add("files[0].content", "Hello, world")
+	form.Add("files[0].language", "auto")
+	form.Add("expires", "1d")
+	form.Add("visibility", models.VisibilityPublic)
+	form.Add("format", models.FormatPlain)
+	form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+
+	code, header, _ := ts.postForm(t, "/snippet/create", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d; want %d", code, http.StatusSeeOther)
+	}
+	if location := header.Get("Location"); location != "/snippet/view/1" {
+		t.Fatalf("got redirect to %q; want %q", location, "/snippet/view/1")
+	}
+
+	snippet, err := app.snippets.Get(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snippet.OwnerID != 1 {
+		t.Errorf("got owner %d; want %d", snippet.OwnerID, 1)
+	}
+}
+
+func TestSnippetModify(t *testing.T) {
+	// Alice owns the snippets, Bob is another user and Carol is an admin.
+	// Logging in is slow, because of bcrypt, so each of them logs in once.
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+	insertUser(t, app, "Carol", "carol@example.com")
+
+	err := app.users.SetRole("carol@example.com", models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := map[string]*testServer{"": newTestServer(t, app.routes())}
+	for _, email := range []string{"alice@example.com", "bob@example.com", "carol@example.com"} {
+		servers[email] = newTestServer(t, app.routes())
+		servers[email].logIn(t, email, "pa$$word")
+	}
+
+	tests := []struct {
+		name       string
+		email      string
+		visibility string
+		wantCode   int
+	}{
+		{name: "Owner", email: "alice@example.com", visibility: models.VisibilityPublic, wantCode: http.StatusSeeOther},
+		{name: "Owner, private snippet", email: "alice@example.com", visibility: models.VisibilityPrivate, wantCode: http.StatusSeeOther},
+		{name: "Other user", email: "bob@example.com", visibility: models.VisibilityPublic, wantCode: http.StatusForbidden},
+		{name: "Other user, unlisted snippet", email: "bob@example.com", visibility: models.VisibilityUnlisted, wantCode: http.StatusForbidden},
+		{name: "Other user, private snippet", email: "bob@example.com", visibility: models.VisibilityPrivate, wantCode: http.StatusNotFound},
+		{name: "Admin", email: "carol@example.com", visibility: models.VisibilityPublic, wantCode: http.StatusSeeOther},
+		{name: "Admin, unlisted snippet", email: "carol@example.com", visibility: models.VisibilityUnlisted, wantCode: http.StatusSeeOther},
+		{name: "Admin, private snippet", email: "carol@example.com", visibility: models.VisibilityPrivate, wantCode: http.StatusSeeOther},
+		{name: "Logged out", visibility: models.VisibilityPublic, wantCode: http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		// Only a logged in owner or admin changes anything; a logged out user
+		// is sent to log in.
+		wantChanged := tt.wantCode == http.StatusSeeOther && tt.email != ""
+
+		t.Run("Edit/"+tt.name, func(t *testing.T) {
+			snippet := insertSnippet(t, app, "Snippet", tt.visibility, 1)
+			ts := servers[tt.email]
+
+			form := url.Values{}
+			form.Add("title", "Changed")
+			form.Add("content", "Changed content")
+			form.Add("language", "auto")
+			form.Add("visibility", tt.visibility)
+			form.Add("format", models.FormatPlain)
+			form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+			code, _, _ := ts.postForm(t, "/snippet/edit/"+snippet.Ref(), form)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			stored, err := app.snippets.Get(snippet.ID, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := stored.Title == "Changed"; changed != wantChanged {
+				t.Errorf("got title %q after status %d", stored.Title, code)
+			}
+		})
+
+		t.Run("Delete/"+tt.name, func(t *testing.T) {
+			snippet := insertSnippet(t, app, "Snippet", tt.visibility, 1)
+			ts := servers[tt.email]
+
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+			code, _, _ := ts.postForm(t, "/snippet/delete/"+snippet.Ref(), form)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			_, err := app.snippets.Get(snippet.ID, 1)
+			if deleted := err != nil; deleted != wantChanged {
+				t.Errorf("got deleted %t after status %d", deleted, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// expiresBurnAfterReading is the value of the expires field on the create form
+// for a snippet which is deleted the first time someone views it. If nobody
+// does, it expires after burnAfterReadingLifetime like any other snippet.
+const (
+	expiresBurnAfterReading  = "burn"
+	burnAfterReadingLifetime = 7 * 24 * time.Hour
+)
+
+// burnLifetime() returns how long an unread burn-after-reading snippet is kept:
+// burnAfterReadingLifetime, or maxLifetime if that is shorter, where zero means
+// there is no maximum. It is also used as a template function, for the hint on
+// the create form.
+func burnLifetime(maxLifetime time.Duration) time.Duration {
+	if maxLifetime != 0 && maxLifetime < burnAfterReadingLifetime {
+		return maxLifetime
+	}
+	return burnAfterReadingLifetime
+}
+
+// contentHidden() returns true if the snippet's content mustn't be shown to the
+// user on any page but snippetView, which explains why. That is the case for a
+// protected snippet until the user unlocks it, and for a burn-after-reading
+// snippet, which only its owner can see without burning it.
+func (app *application) contentHidden(r *http.Request, snippet *models.Snippet) bool {
+	if !app.isUnlocked(r, snippet) {
+		return true
+	}
+
+	return snippet.BurnAfterReading && !snippet.OwnedBy(app.authenticatedUserID(r))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"snippetbox.sangdennis.com/internal/migrations"
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// runCommand() runs the command named by the non-flag command-line arguments,
+// for example:
+//
+//	snippetbox -dsn=... migrate up
+//	snippetbox -dsn=... migrate down
+//	snippetbox -dsn=... migrate status
+//	snippetbox -dsn=... purge
+//	snippetbox -dsn=... role alice@example.com admin
+func runCommand(args []string, infoLog *log.Logger, migrator *migrations.Migrator, snippets models.SnippetStore, users models.UserStore, batchSize int) error {
+	switch args[0] {
+	case "migrate":
+		return migrateCommand(args[1:], infoLog, migrator)
+	case "purge":
+		return purgeCommand(args[1:], infoLog, snippets, batchSize)
+	case "role":
+		return roleCommand(args[1:], infoLog, users)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func migrateCommand(args []string, infoLog *log.Logger, migrator *migrations.Migrator) error {
+	if migrator == nil {
+		return errors.New("migrate needs a SQL store; use -store=sql")
+	}
+
+	if len(args) != 1 {
+		return errors.New("usage: migrate up|down|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			infoLog.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			infoLog.Print("Schema is up to date")
+		}
+
+	case "down":
+		m, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		infoLog.Printf("Rolled back %04d_%s", m.Version, m.Name)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("%04d_%s\tapplied %s\n", s.Version, s.Name, humanDate(s.AppliedAt))
+			} else {
+				fmt.Printf("%04d_%s\tpending\n", s.Version, s.Name)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (must be up, down or status)", args[0])
+	}
+
+	return nil
+}
+
+// purgeCommand() deletes all expired snippets once, the same way the background
+// reaper does, for deployments which prefer to run it from cron.
+func purgeCommand(args []string, infoLog *log.Logger, snippets models.SnippetStore, batchSize int) error {
+	if len(args) != 0 {
+		return errors.New("usage: purge")
+	}
+
+	n, err := purgeExpired(snippets, batchSize)
+	if err != nil {
+		return err
+	}
+
+	infoLog.Printf("Purged %d expired snippets", n)
+	return nil
+}
+
+// roleCommand() gives the user with an email address a role. There is no page
+// for managing users, so this is how the first admin is made.
+func roleCommand(args []string, infoLog *log.Logger, users models.UserStore) error {
+	if len(args) != 2 {
+		return errors.New("usage: role EMAIL user|admin")
+	}
+
+	email, role := strings.ToLower(args[0]), args[1]
+
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return fmt.Errorf("unknown role %q (must be user or admin)", role)
+	}
+
+	err := users.SetRole(email, role)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("no user with email address %q", email)
+		}
+		return err
+	}
+
+	infoLog.Printf("%s now has the %s role", email, role)
+	return nil
+}
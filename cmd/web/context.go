@@ -0,0 +1,9 @@
+package main
+
+// contextKey is the type of the keys used to store values in a request context,
+// so they can't collide with keys set by other packages.
+type contextKey string
+
+const isAuthenticatedContextKey = contextKey("isAuthenticated")
+const authenticatedUserIDContextKey = contextKey("authenticatedUserID")
+const isAdminContextKey = contextKey("isAdmin")
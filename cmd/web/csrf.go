@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+)
+
+// csrfSessionKey is the session key holding the session's CSRF token, and
+// csrfFieldName the name of the hidden form field the token is sent back in.
+// Scripts can send it in the csrfHeaderName header instead.
+const (
+	csrfSessionKey = "csrfToken"
+	csrfFieldName  = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// csrfToken() returns the CSRF token for the user's session, creating one the
+// first time it is needed.
+func (app *application) csrfToken(r *http.Request) string {
+	token := app.sessionManager.GetString(r.Context(), csrfSessionKey)
+	if token != "" {
+		return token
+	}
+
+	b := make([]byte, 32)
+
+	// crypto/rand only fails if the operating system can't provide random
+	// numbers at all, which there is no sensible way to carry on from. The
+	// recoverPanic middleware turns this into a 500 response.
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	app.sessionManager.Put(r.Context(), csrfSessionKey, token)
+
+	return token
+}
+
+// validCSRFRequest() returns true if a state-changing request was made by one
+// of our own pages: the browser must not say it came from another site, and it
+// must carry the session's CSRF token.
+func (app *application) validCSRFRequest(r *http.Request) bool {
+	// Browsers which send Sec-Fetch-Site tell us directly where the request
+	// came from. "none" means the user typed or bookmarked the URL.
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return false
+	}
+
+	// Otherwise fall back to comparing the Origin header, when there is one,
+	// with the host the request was sent to.
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return false
+		}
+	}
+
+	expected := app.sessionManager.GetString(r.Context(), csrfSessionKey)
+	if expected == "" {
+		return false
+	}
+
+	token := r.Header.Get(csrfHeaderName)
+	if token == "" {
+		token = r.PostFormValue(csrfFieldName)
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// forbidden() renders the 403 Forbidden page, which is shown when a form is
+// submitted without a valid CSRF token, or a user tries to change a snippet
+// which isn't theirs.
+func (app *application) forbidden(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	app.render(w, http.StatusForbidden, "forbidden.html", data)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+func TestPreventCSRF(t *testing.T) {
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+	ts.logIn(t, "alice@example.com", "pa$$word")
+	token := ts.csrfToken(t, "/user/login")
+
+	// A token from somebody else's session.
+	other := newTestServer(t, app.routes())
+	otherToken := other.csrfToken(t, "/user/login")
+
+	tests := []struct {
+		name     string
+		token    string
+		header   http.Header
+		wantCode int
+	}{
+		{name: "Valid token", token: token, wantCode: http.StatusSeeOther},
+		{name: "Valid token in header", header: http.Header{"X-Csrf-Token": {token}}, wantCode: http.StatusSeeOther},
+		{name: "Same origin", token: token, header: http.Header{"Sec-Fetch-Site": {"same-origin"}}, wantCode: http.StatusSeeOther},
+		{name: "No token", wantCode: http.StatusForbidden},
+		{name: "Wrong token", token: "not-the-token", wantCode: http.StatusForbidden},
+		{name: "Token from another session", token: otherToken, wantCode: http.StatusForbidden},
+		{name: "Cross-site request", token: token, header: http.Header{"Sec-Fetch-Site": {"cross-site"}}, wantCode: http.StatusForbidden},
+		{name: "Other origin", token: token, header: http.Header{"Origin": {"https://evil.example.com"}}, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet := insertSnippet(t, app, "To delete", models.VisibilityPublic, 1)
+
+			form := url.Values{}
+			if tt.token != "" {
+				form.Add("csrf_token", tt.token)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/snippet/delete/"+strconv.Itoa(snippet.ID), strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, _, _ := readResponse(t, rs)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			_, err = app.snippets.Get(snippet.ID, 1)
+			if deleted := err != nil; deleted != (tt.wantCode == http.StatusSeeOther) {
+				t.Errorf("got deleted %t after status %d", deleted, code)
+			}
+		})
+	}
+}
+
+func TestLoginNeedsCSRFToken(t *testing.T) {
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+
+	// Even logging in needs a token, so another site can't log the user in to
+	// an account of its choosing.
+	form := url.Values{}
+	form.Add("email", "alice@example.com")
+	form.Add("password", "pa$$word")
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusForbidden {
+		t.Errorf("got status %d; want %d", code, http.StatusForbidden)
+	}
+}
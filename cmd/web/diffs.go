@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"snippetbox.sangdennis.com/internal/diff"
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// snippetDiff holds the differences between two revisions of a snippet, ready
+// to be rendered by diff.html or written out as a unified diff.
+type snippetDiff struct {
+	From    *models.Revision
+	To      *models.Revision
+	Mode    string
+	Title   []diff.Hunk
+	Content []diff.Hunk
+}
+
+// Changed() reports whether anything differs between the two revisions.
+func (sd *snippetDiff) Changed() bool {
+	return len(sd.Title) > 0 || len(sd.Content) > 0
+}
+
+// newSnippetDiff() fetches the from and to revisions of the snippet with the
+// given id and diffs their titles and contents.
+func (app *application) newSnippetDiff(id, from, to int) (*snippetDiff, error) {
+	fromRev, err := app.snippets.Revision(id, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toRev, err := app.snippets.Revision(id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snippetDiff{
+		From:    fromRev,
+		To:      toRev,
+		Title:   diffText(fromRev.Title, toRev.Title),
+		Content: diffText(fromRev.Content, toRev.Content),
+	}, nil
+}
+
+func diffText(old, new string) []diff.Hunk {
+	lines := diff.Lines(diff.SplitLines(old), diff.SplitLines(new))
+	return diff.Hunks(lines, diffContext)
+}
+
+// writeUnified() writes the title and content diffs to w as a unified diff,
+// treating them as two files named after the snippet and revision.
+func (sd *snippetDiff) writeUnified(w io.Writer) error {
+	name := func(r *models.Revision, field string) string {
+		return fmt.Sprintf("snippet-%d/r%d/%s", r.SnippetID, r.Number, field)
+	}
+
+	err := diff.WriteUnified(w, name(sd.From, "title"), name(sd.To, "title"), sd.Title)
+	if err != nil {
+		return err
+	}
+
+	return diff.WriteUnified(w, name(sd.From, "content"), name(sd.To, "content"), sd.Content)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/validator"
+)
+
+// Besides the presets, the expires field on the create form can be "never",
+// "burn" (see burn.go) or "custom", which takes its lifetime from what is typed
+// into the expires_custom field.
+const (
+	expiresNever  = "never"
+	expiresCustom = "custom"
+)
+
+// minLifetime is the shortest lifetime a snippet can have, and
+// absoluteMaxLifetime the longest when no -max-lifetime is configured; anything
+// longer might as well never expire.
+const (
+	minLifetime         = time.Minute
+	absoluteMaxLifetime = 100 * 365 * 24 * time.Hour
+)
+
+// expiryPreset is one of the fixed lifetimes offered on the create form.
+type expiryPreset struct {
+	Value    string
+	Label    string
+	Lifetime time.Duration
+}
+
+// expiryPresets lists the fixed lifetimes, longest first.
+var expiryPresets = []expiryPreset{
+	{Value: "365d", Label: "One Year", Lifetime: 365 * 24 * time.Hour},
+	{Value: "7d", Label: "One Week", Lifetime: 7 * 24 * time.Hour},
+	{Value: "1d", Label: "One Day", Lifetime: 24 * time.Hour},
+	{Value: "1h", Label: "One Hour", Lifetime: time.Hour},
+}
+
+// permittedPresets() returns the presets no longer than maxLifetime, where zero
+// means there is no maximum. It is used as a template function, so that the
+// create form only offers lifetimes it will accept.
+func permittedPresets(maxLifetime time.Duration) []expiryPreset {
+	presets := []expiryPreset{}
+
+	for _, p := range expiryPresets {
+		if maxLifetime == 0 || p.Lifetime <= maxLifetime {
+			presets = append(presets, p)
+		}
+	}
+
+	return presets
+}
+
+// defaultExpiry() returns the expiry option selected when the create form is
+// first shown: the longest permitted preset.
+func defaultExpiry(maxLifetime time.Duration) string {
+	presets := permittedPresets(maxLifetime)
+	if len(presets) == 0 {
+		return expiresCustom
+	}
+	return presets[0].Value
+}
+
+// checkExpiry() runs the validation checks on the expiry fields of the create
+// form, and returns the lifetime of the new snippet (zero if it never expires)
+// and whether it is burnt after reading. maxLifetime is the configured maximum
+// lifetime, with zero meaning there is none and snippets may never expire. A
+// date and time typed into the custom field is read in the time zone loc.
+func checkExpiry(v *validator.Validator, expires, custom string, loc *time.Location, maxLifetime time.Duration, now time.Time) (time.Duration, bool) {
+	limit := maxLifetime
+	if limit == 0 {
+		limit = absoluteMaxLifetime
+	}
+	tooLong := fmt.Sprintf("Snippets can't be kept for longer than %s.", humanDuration(limit))
+
+	switch expires {
+	case expiresNever:
+		v.CheckField(maxLifetime == 0, "expires", tooLong)
+		return 0, false
+
+	case expiresBurnAfterReading:
+		// Don't keep an unread snippet for longer than the maximum either.
+		return burnLifetime(maxLifetime), true
+
+	case expiresCustom:
+		custom = strings.TrimSpace(custom)
+		between := fmt.Sprintf("This field must be between %s and %s from now.", humanDuration(minLifetime), humanDuration(limit))
+
+		if !validator.NotBlank(custom) {
+			v.AddFieldError("expires_custom", "This field cannot be blank.")
+			return 0, false
+		}
+
+		if t, ok := parseExpiryTime(custom, loc); ok {
+			v.CheckField(validator.TimeBetween(t, now.Add(minLifetime), now.Add(limit)), "expires_custom", between)
+			return t.Sub(now), false
+		}
+
+		d, err := parseLifetime(custom)
+		if err != nil {
+			v.AddFieldError("expires_custom", "This field must be a duration like 90m or 30d, or a date and time like 2030-12-31 18:00.")
+			return 0, false
+		}
+
+		v.CheckField(validator.DurationBetween(d, minLifetime, limit), "expires_custom", between)
+		return d, false
+	}
+
+	for _, p := range expiryPresets {
+		if p.Value == expires {
+			v.CheckField(p.Lifetime <= limit, "expires", tooLong)
+			return p.Lifetime, false
+		}
+	}
+
+	v.AddFieldError("expires", "This field must be one of the options shown.")
+	return 0, false
+}
+
+// parseLifetime() parses a duration understood by time.ParseDuration(), such as
+// "90m" or "1h30m", or a whole number of days or weeks, such as "30d" or "2w".
+func parseLifetime(input string) (time.Duration, error) {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"w", 7 * 24 * time.Hour},
+	}
+
+	for _, u := range units {
+		number, found := strings.CutSuffix(input, u.suffix)
+		if !found {
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid lifetime %q", input)
+		}
+
+		// Stop a huge number overflowing into a short or negative duration;
+		// the longest duration there is will still fail the range check.
+		if n > int(math.MaxInt64/int64(u.unit)) {
+			return math.MaxInt64, nil
+		}
+
+		return time.Duration(n) * u.unit, nil
+	}
+
+	return time.ParseDuration(input)
+}
+
+// clientZone() returns the time zone described by the expires_offset field of
+// the create form, which main.js fills in with the browser's offset from UTC in
+// minutes. Without JavaScript the field is empty, and dates and times are read
+// in UTC, as the form says; an offset no real time zone has is treated the same.
+func clientZone(offset string) *time.Location {
+	minutes, err := strconv.Atoi(strings.TrimSpace(offset))
+	if err != nil || minutes < -14*60 || minutes > 14*60 {
+		return time.UTC
+	}
+	return time.FixedZone("", minutes*60)
+}
+
+// parseExpiryTime() parses a date, or a date and time, in the time zone loc. It
+// accepts the format sent by datetime-local inputs as well as a space-separated
+// one.
+func parseExpiryTime(input string, loc *time.Location) (time.Time, bool) {
+	layouts := []string{
+		"2006-01-02 15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+	}
+
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, input, loc)
+		if err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
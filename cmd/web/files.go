@@ -0,0 +1,120 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+
+	"snippetbox.sangdennis.com/internal/highlight"
+	"snippetbox.sangdennis.com/internal/models"
+	"snippetbox.sangdennis.com/internal/validator"
+)
+
+// maxFiles is the most files a snippet can have.
+const maxFiles = 10
+
+// snippetFileForm is one of the file entries on the create form. They are sent
+// as files[0].name, files[0].language, files[0].content, files[1].name and so
+// on, and main.js keeps the numbers in order as entries are added and removed.
+type snippetFileForm struct {
+	Name     string `form:"name"`
+	Language string `form:"language"`
+	Content  string `form:"content"`
+}
+
+// dropBlankFiles() returns the file entries which have a name or content, so
+// that an entry added and then left empty doesn't count. If none do, the first
+// entry is kept, for the validation checks to complain about.
+func dropBlankFiles(entries []snippetFileForm) []snippetFileForm {
+	kept := []snippetFileForm{}
+
+	for _, f := range entries {
+		if validator.NotBlank(f.Name) || validator.NotBlank(f.Content) {
+			kept = append(kept, f)
+		}
+	}
+
+	if len(kept) == 0 {
+		if len(entries) > 0 {
+			return entries[:1]
+		}
+		return []snippetFileForm{{Language: highlight.Auto}}
+	}
+
+	return kept
+}
+
+// checkFiles() runs the validation checks on the file entries of the create
+// form, and returns the files to store. The errors for an entry are stored
+// under keys like "files.0.content", and those about the files as a whole
+// under "files". A file name is optional for a snippet with only one file, but
+// each file of a bundle needs its own.
+func checkFiles(v *validator.Validator, entries []snippetFileForm) []models.File {
+	v.CheckField(len(entries) <= maxFiles, "files", fmt.Sprintf("There can be no more than %d files.", maxFiles))
+
+	files := []models.File{}
+	seen := map[string]bool{}
+
+	for i, f := range entries {
+		prefix := fmt.Sprintf("files.%d.", i)
+		name := strings.TrimSpace(f.Name)
+
+		checkFile(v, prefix, name, f.Language, f.Content, len(entries) > 1)
+
+		// Names which differ only by case would clash when the zip is
+		// extracted on some systems.
+		key := strings.ToLower(name)
+		if name != "" && seen[key] {
+			v.AddFieldError(prefix+"name", "Each file must have a different name.")
+		}
+		seen[key] = true
+
+		files = append(files, models.File{Name: name, Language: f.Language, Content: f.Content})
+	}
+
+	return files
+}
+
+// checkFile() runs the validation checks on the name, language and content of
+// a file, storing any errors under the keys prefix+"name", prefix+"language" and
+// prefix+"content". The edit form, which has no name field, uses an empty
+// prefix and name.
+func checkFile(v *validator.Validator, prefix, name, language, content string, nameRequired bool) {
+	if nameRequired {
+		v.CheckField(validator.NotBlank(name), prefix+"name", "Each file needs a name when there is more than one.")
+	}
+	if name != "" {
+		v.CheckField(validator.MaxChars(name, 100), prefix+"name", "This field cannot be more than 100 characters long.")
+		v.CheckField(validator.Matches(name, validator.FilenameRX), prefix+"name", "File names may only contain letters, digits, dots, underscores and hyphens.")
+	}
+
+	v.CheckField(highlight.Known(language), prefix+"language", "This field must be one of the languages listed.")
+	v.CheckField(validator.NotBlank(content), prefix+"content", "This field cannot be blank.")
+}
+
+// writeZip() writes all the files of a snippet to w as a zip archive, each
+// under the name it is downloaded as, and dated when the snippet was created.
+func writeZip(w io.Writer, snippet *models.Snippet) error {
+	zw := zip.NewWriter(w)
+
+	for i := range snippet.Files {
+		header := &zip.FileHeader{
+			Name:     snippetFilename(snippet, &snippet.Files[i]),
+			Method:   zip.Deflate,
+			Modified: snippet.Created,
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(fw, snippet.Files[i].Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
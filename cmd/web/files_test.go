@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+func TestSnippetCreateFileCount(t *testing.T) {
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+	ts.logIn(t, "alice@example.com", "pa$$word")
+
+	tests := []struct {
+		name     string
+		indexes  []int
+		wantCode int
+		wantBody string
+	}{
+		{name: "One file", indexes: []int{0}, wantCode: http.StatusSeeOther},
+		{name: "Most files", indexes: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, wantCode: http.StatusSeeOther},
+		{name: "Too many files", indexes: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, wantCode: http.StatusUnprocessableEntity, wantBody: "There can be no more than 10 files."},
+		{name: "Index out of range", indexes: []int{0, 99999}, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Bundle")
+			form.Add("expires", "1d")
+			form.Add("visibility", models.VisibilityPublic)
+			form.Add("format", models.FormatPlain)
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+			for _, i := range tt.indexes {
+				form.Add(fmt.Sprintf("files[%d].name", i), fmt.Sprintf("file%d.txt", i))
+				form.Add(fmt.Sprintf("files[%d].language", i), "auto")
+				form.Add(fmt.Sprintf("files[%d].content", i), "Content")
+			}
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got body without %q", tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"net/http"
+)
+
+// The levels of flash message, which are also used as CSS classes.
+const (
+	flashSuccess = "success"
+	flashWarning = "warning"
+	flashError   = "error"
+)
+
+// flashSessionKey is the session key holding the queue of flash messages.
+const flashSessionKey = "flash"
+
+// flash is a one-time message shown to the user on the next page they see,
+// usually after a redirect.
+type flash struct {
+	Level   string
+	Message string
+}
+
+func init() {
+	// The session store encodes values with gob, which needs to know about any
+	// types other than the basic ones.
+	gob.Register([]flash{})
+}
+
+// addFlash() queues a flash message for the next page rendered in the user's
+// session. The message is formatted like fmt.Sprintf().
+func (app *application) addFlash(r *http.Request, level, format string, args ...any) {
+	flashes, _ := app.sessionManager.Get(r.Context(), flashSessionKey).([]flash)
+	flashes = append(flashes, flash{Level: level, Message: fmt.Sprintf(format, args...)})
+
+	app.sessionManager.Put(r.Context(), flashSessionKey, flashes)
+}
+
+// popFlashes() returns the queued flash messages, oldest first, and removes them
+// from the session so that each is only shown once.
+func (app *application) popFlashes(r *http.Request) []flash {
+	flashes, _ := app.sessionManager.Pop(r.Context(), flashSessionKey).([]flash)
+	return flashes
+}
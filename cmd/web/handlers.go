@@ -1,17 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"snippetbox.sangdennis.com/internal/highlight"
 	"snippetbox.sangdennis.com/internal/models"
 	"snippetbox.sangdennis.com/internal/validator"
 )
 
 // Change the signature of home() to be defined as a method against *application.
+// It serves both the home page and the /snippets listing, which are paginated
+// with the ?after= and ?before= cursors.
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	// Because httprouter matches the "/" path exactly, we can now remove the manual
 	// check below from this handler.
@@ -20,7 +29,35 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	// 	return
 	// }
 
-	snippets, err := app.snippets.Latest()
+	// Read the optional pagination cursors and page size from the query string.
+	after, err := app.queryInt(r, "after")
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	before, err := app.queryInt(r, "before")
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	limit, err := app.queryInt(r, "limit")
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Fall back to the configured page size, and never let a client ask for
+	// more than maxPageSize snippets at once.
+	if limit <= 0 {
+		limit = app.pageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	page, err := app.snippets.Page(after, before, limit)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -28,30 +65,66 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 
 	// Call the newTemplateData() helper to get a templateData struct containing
 	// the 'default' data (which for now is just the current year), and add the
-	// snippets slice to it.
+	// page of snippets to it.
 	data := app.newTemplateData(r)
-	data.Snippets = snippets
+	data.Snippets = page.Snippets
+	data.Page = page
+
+	// Show a cloud of the most used tags alongside the listing.
+	data.Tags, err = app.snippets.TopTags(tagCloudSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
 	// Use the render helper
 	app.render(w, http.StatusOK, "home.html", data)
 }
 
 func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-	// When httprouter is parsing a request, the values of any named parameters
-	// will be stored in the request context.
-	// You can use ParamsFromContext() function to retrieve a slice containing
-	// these parameter names and values like so:
-	params := httprouter.ParamsFromContext(r.Context())
+	// The snippet is named by the "id" parameter in the URL, which holds either
+	// its id or, for an unlisted snippet, its slug. Snippets the user isn't
+	// allowed to see are treated as if they don't exist.
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
 
-	// We can then use the ByName() method to get the value of the "id" named
-	// parameter from the slice and validate it as normal.
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil || id < 1 {
-		app.notFound(w)
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// Ask for the passphrase instead of showing a protected snippet, unless
+	// it has already been given.
+	if !app.isUnlocked(r, snippet) {
+		data.Form = snippetUnlockForm{}
+		app.render(w, http.StatusOK, "unlock.html", data)
+		return
+	}
+
+	// Viewing a burn-after-reading snippet deletes it, so it takes a second
+	// step: a POST from the button on reveal.html. Link previews and crawlers
+	// only ever GET the page, so they can't burn it by accident. Its owner can
+	// see it without burning it.
+	if app.contentHidden(r, snippet) {
+		app.render(w, http.StatusOK, "reveal.html", data)
 		return
 	}
 
-	snippet, err := app.snippets.Get(id)
+	data.CanModify = app.canModify(r, snippet)
+
+	// Use the new render helper.
+	app.render(w, http.StatusOK, "view.html", data)
+}
+
+// snippetRevealPost shows a burn-after-reading snippet, deleting it in the
+// same step, so that nobody else can ever see it.
+func (app *application) snippetRevealPost(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippetParam(r)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
@@ -61,21 +134,300 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Nothing to reveal here: the snippet is either still locked, or isn't
+	// burnt by viewing it.
+	if !app.isUnlocked(r, snippet) || !app.contentHidden(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	// Burn() returns ErrNoRecord if someone else has just revealed it.
+	snippet, err = app.snippets.Burn(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Add the warning before newTemplateData() collects the flashes, so that
+	// it appears on this page.
+	app.addFlash(r, flashWarning, "This snippet has now been deleted. Copy anything you need from it before leaving the page.")
+
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
 
-	// Use the new render helper.
+	// The page is the only copy of the snippet left, so it mustn't be cached.
+	w.Header().Set("Cache-Control", "no-store")
 	app.render(w, http.StatusOK, "view.html", data)
 }
 
+// snippetRaw sends just the content of a snippet, as plain text, so that it
+// can be fetched with curl or piped into other tools. The file query string
+// parameter picks which of the snippet's files to send, e.g. ?file=2, and the
+// lines parameter a single line or a range of them, e.g. ?lines=12-20.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// There's no page to unlock or reveal the snippet on here, so refuse.
+	if app.contentHidden(r, snippet) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	file, err := app.fileParam(r, snippet)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.clientError(w, http.StatusBadRequest)
+		}
+		return
+	}
+
+	content := file.Content
+
+	if lines := r.URL.Query().Get("lines"); lines != "" {
+		content, err = selectLines(content, lines)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// secureHeaders has already set X-Content-Type-Options: nosniff, so
+	// browsers won't treat content which looks like HTML as HTML.
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, content)
+}
+
+// snippetDownload sends one of a snippet's files to save, picked by the file
+// query string parameter in the same way as for snippetRaw.
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if app.contentHidden(r, snippet) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	file, err := app.fileParam(r, snippet)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.clientError(w, http.StatusBadRequest)
+		}
+		return
+	}
+
+	// FormatMediaType() quotes the file name, and encodes it as RFC 2231
+	// requires if it isn't plain ASCII.
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": snippetFilename(snippet, file)})
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", disposition)
+	io.WriteString(w, file.Content)
+}
+
+// snippetZip sends all of a snippet's files together, as a zip archive named
+// after its title.
+func (app *application) snippetZip(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if app.contentHidden(r, snippet) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	// Build the archive in a buffer first, so that an error can still be
+	// sent as a 500 rather than a truncated zip.
+	buf := new(bytes.Buffer)
+
+	err = writeZip(buf, snippet)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": titleSlug(snippet) + ".zip"})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", disposition)
+	buf.WriteTo(w)
+}
+
+// snippetUnlockForm represents the form for the passphrase of a protected
+// snippet.
+type snippetUnlockForm struct {
+	Passphrase          string `form:"passphrase"`
+	validator.Validator `form:"-"`
+}
+
+// snippetUnlockPost checks the passphrase for a protected snippet, and if it is
+// right remembers in the session that the snippet is unlocked. Wrong guesses
+// are rate limited, per snippet and per client IP address.
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	var form snippetUnlockForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// Check the limits before the passphrase, so that a blocked client learns
+	// nothing from guessing. The attempt counts as a failure unless it turns
+	// out to be something else.
+	ok, wait := app.attemptUnlock(r, snippet)
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		form.AddNonFieldError("Too many wrong passphrases. Please try again later.")
+		form.Passphrase = ""
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "unlock.html", data)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Passphrase), "passphrase", "This field cannot be blank.")
+
+	if !form.Valid() {
+		app.refundUnlock(r, snippet)
+	} else {
+		err = app.snippets.CheckPassphrase(snippet.ID, form.Passphrase)
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			app.refundUnlock(r, snippet)
+		}
+
+		if err == nil {
+			app.markUnlocked(r, snippet.ID)
+			http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+			return
+		}
+
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return
+		}
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			app.serverError(w, err)
+			return
+		}
+
+		form.AddFieldError("passphrase", "Passphrase is incorrect.")
+	}
+
+	form.Passphrase = ""
+	data.Form = form
+	app.render(w, http.StatusUnprocessableEntity, "unlock.html", data)
+}
+
+// snippetSearch shows the snippets matching the ?q= search query, with the
+// matching terms highlighted.
+func (app *application) snippetSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	if !validator.MaxChars(query, 100) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Query = query
+
+	// Only hit the database if there's something to search for.
+	if query != "" {
+		snippets, err := app.snippets.Search(query, maxSearchResults)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		data.Snippets = snippets
+	}
+
+	app.render(w, http.StatusOK, "search.html", data)
+}
+
+// tagView lists the newest snippets with the tag named in the URL.
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag := params.ByName("name")
+	if !validator.Matches(tag, validator.TagRX) {
+		app.notFound(w)
+		return
+	}
+
+	snippets, err := app.snippets.ByTag(tag, maxPageSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "tag.html", data)
+}
+
 // Add a new snippetCreate handler, which for now returns a placeholder response.
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 
 	// Initialize a new createSnippetForm instance and pass it to the template.
-	// Set any default values for the form e.g snippet expiry to 365 days.
+	// Set any default values for the form e.g snippet expiry to the longest
+	// preset the maximum lifetime allows.
 	data.Form = snippetCreateForm{
-		Expires: 365,
+		Files:       []snippetFileForm{{Language: highlight.Auto}},
+		Expires:     defaultExpiry(app.maxLifetime),
+		Visibility:  models.VisibilityPublic,
+		Format:      models.FormatCode,
+		MaxLifetime: app.maxLifetime,
 	}
 
 	app.render(w, http.StatusOK, "create.html", data)
@@ -89,13 +441,46 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 // all the fields and methods of Validator type (including FieldErrors field)
 // Update snippetCreateForm struct to include struct tags which tell the decoder how to
 // map HTML form values into the different struct fields.
+// Tags holds the tags exactly as typed (comma or space separated), so that the
+// form can be re-populated with them. Files holds an entry for each of the
+// snippet's files (see files.go). MaxLifetime isn't a form field, but tells
+// the template which expiry options to offer.
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
+	Title               string            `form:"title"`
+	Files               []snippetFileForm `form:"files"`
+	Tags                string            `form:"tags"`
+	Expires             string            `form:"expires"`
+	ExpiresCustom       string            `form:"expires_custom"`
+	ExpiresOffset       string            `form:"expires_offset"`
+	Visibility          string            `form:"visibility"`
+	Format              string            `form:"format"`
+	Passphrase          string            `form:"passphrase"`
+	MaxLifetime         time.Duration     `form:"-"`
 	validator.Validator `form:"-"`
 }
 
+// checkSnippet() runs the validation checks shared by the create and edit forms
+// on the title, tags, visibility and format fields, and returns the parsed tags.
+// The files are checked by checkFiles() or checkFile().
+func checkSnippet(v *validator.Validator, title, tagsInput, visibility, format string) []string {
+	v.CheckField(validator.NotBlank(title), "title", "This field cannot be blank.")
+	v.CheckField(validator.MaxChars(title, 100), "title", "This field cannot be more than 100 characters long.")
+
+	tags := parseTags(tagsInput)
+	v.CheckField(validator.MaxItems(tags, maxTags), "tags", fmt.Sprintf("There can be no more than %d tags.", maxTags))
+	for _, tag := range tags {
+		v.CheckField(validator.MaxChars(tag, 20), "tags", "Tags cannot be more than 20 characters long.")
+		v.CheckField(validator.Matches(tag, validator.TagRX), "tags", "Tags may only contain letters, digits and hyphens.")
+	}
+
+	v.CheckField(validator.PermittedValue(visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate),
+		"visibility", "This field must equal public, unlisted or private.")
+	v.CheckField(validator.PermittedValue(format, models.FormatPlain, models.FormatCode, models.FormatMarkdown),
+		"format", "This field must equal plain, code or markdown.")
+
+	return tags
+}
+
 func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
 	// Declare a new instance of the snippetCreateForm struct.
 	var form snippetCreateForm
@@ -110,27 +495,523 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	// is embedded by the snippetCreateForm struct.
 	// CheckField() adds the provided key and error message to the FieldErrors map if
 	// the check does not evaluate to true.
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank.")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long.")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank.")
-	form.CheckField(validator.PermittedInt(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365.")
+	form.Files = dropBlankFiles(form.Files)
+	tags := checkSnippet(&form.Validator, form.Title, form.Tags, form.Visibility, form.Format)
+	files := checkFiles(&form.Validator, form.Files)
+	lifetime, burnAfterReading := checkExpiry(&form.Validator, form.Expires, form.ExpiresCustom, clientZone(form.ExpiresOffset), app.maxLifetime, time.Now())
+
+	// The passphrase is optional, but must be hard to guess if it is given. Like
+	// passwords, bcrypt ignores anything after the first 72 bytes of it.
+	if form.Passphrase != "" {
+		form.CheckField(validator.MinChars(form.Passphrase, 8), "passphrase", "This field must be at least 8 characters long.")
+		form.CheckField(len(form.Passphrase) <= 72, "passphrase", "This field cannot be more than 72 bytes long.")
+	}
 
 	// Use the Valid() method to see if any of the checks failed.
 	// If they did, re-render the template, passing in the form in the same way as before.
+	// The passphrase is never sent back to the browser.
 	if !form.Valid() {
+		form.Passphrase = ""
+		form.MaxLifetime = app.maxLifetime
 		data := app.newTemplateData(r)
 		data.Form = form
 		app.render(w, http.StatusUnprocessableEntity, "create.html", data)
 		return
 	}
 
-	// Pass the data from snippetCreateForm instance to Insert() method
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	// Pass the data from snippetCreateForm instance to Insert() method, making the
+	// logged in user the owner of the new snippet. A lifetime of zero means the
+	// snippet never expires.
+	id, err := app.snippets.Insert(models.NewSnippet{
+		Title:            form.Title,
+		Files:            files,
+		Lifetime:         lifetime,
+		Tags:             tags,
+		OwnerID:          app.authenticatedUserID(r),
+		Visibility:       form.Visibility,
+		Passphrase:       form.Passphrase,
+		BurnAfterReading: burnAfterReading,
+		Format:           form.Format,
+	})
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
+	// Fetch the new snippet back, for the URL to redirect to: an unlisted
+	// snippet is linked to by its slug rather than its id.
+	snippet, err := app.snippets.Get(id, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Queue a confirmation message for the page we redirect to.
+	app.addFlash(r, flashSuccess, "Snippet successfully created!")
+
 	// Redirect path to use the new clean URL format.
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+}
+
+// snippetEditForm represents the edit form. The expiry time can't be changed
+// after a snippet is created, so unlike snippetCreateForm it has no Expires.
+// Only the content and language of the first file can be edited, so it has no
+// Files either.
+type snippetEditForm struct {
+	ID                  int    `form:"-"`
+	Title               string `form:"title"`
+	Content             string `form:"content"`
+	Tags                string `form:"tags"`
+	Visibility          string `form:"visibility"`
+	Language            string `form:"language"`
+	Format              string `form:"format"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.modifiableSnippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if !app.canModify(r, snippet) {
+		app.forbidden(w, r)
+		return
+	}
+
+	// Send the user to snippetView if they can't see the content yet, where
+	// they can unlock or reveal it.
+	if app.contentHidden(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	// Pre-fill the form with the current version of the snippet.
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetEditForm{
+		ID:         snippet.ID,
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Tags:       strings.Join(snippet.Tags, ", "),
+		Visibility: snippet.Visibility,
+		Language:   snippet.Language,
+		Format:     snippet.Format,
+	}
+
+	app.render(w, http.StatusOK, "edit.html", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.modifiableSnippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if !app.canModify(r, snippet) {
+		app.forbidden(w, r)
+		return
+	}
+
+	// Send the user to snippetView if they can't see the content yet, where
+	// they can unlock or reveal it.
+	if app.contentHidden(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	var form snippetEditForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.ID = snippet.ID
+
+	tags := checkSnippet(&form.Validator, form.Title, form.Tags, form.Visibility, form.Format)
+	checkFile(&form.Validator, "", "", form.Language, form.Content, false)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.html", data)
+		return
+	}
+
+	// Update() stores a new revision if the title or content changed.
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content, tags, form.Visibility, form.Language, form.Format)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.addFlash(r, flashSuccess, "Snippet successfully updated!")
+
+	// The snippet's URL changes if it was made unlisted or listed again, so
+	// fetch it again: snippets from before there were slugs only get one in
+	// Update(). An admin can't view somebody else's private snippet, so goes
+	// home instead.
+	snippet, err = app.snippets.GetForModify(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	if snippet.Visibility == models.VisibilityPrivate && !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+}
+
+// snippetDeletePost deletes a snippet, if the user is allowed to.
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.modifiableSnippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if !app.canModify(r, snippet) {
+		app.forbidden(w, r)
+		return
+	}
+
+	err = app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.addFlash(r, flashSuccess, "Snippet %q deleted.", snippet.Title)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// snippetHistory lists every revision of a snippet, newest first.
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Send the user to snippetView if they can't see the content yet, where
+	// they can unlock or reveal it.
+	if app.contentHidden(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, http.StatusOK, "history.html", data)
+}
+
+// snippetRevision shows a snippet as it was at one of its past revisions.
+func (app *application) snippetRevision(w http.ResponseWriter, r *http.Request) {
+	number, err := app.idParam(r, "rev")
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	snippet, err := app.snippetParam(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Send the user to snippetView if they can't see the content yet, where
+	// they can unlock or reveal it.
+	if app.contentHidden(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	revision, err := app.snippets.Revision(snippet.ID, number)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revision = revision
+
+	app.render(w, http.StatusOK, "revision.html", data)
+}
+
+// snippetDiff shows what changed in a snippet between two revisions, given by
+// the ?from= and ?to= revision numbers. By default it compares the current
+// revision with the one before it. The ?mode= parameter picks a "unified" (the
+// default) or "split" side-by-side view. Adding .diff to the id, as in
+// /snippet/diff/1.diff, downloads the same diff as a plain text unified diff.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	ref, raw := strings.CutSuffix(params.ByName("id"), ".diff")
+
+	snippet, err := app.getSnippet(r, ref)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Send the user to snippetView if they can't see the content yet, where
+	// they can unlock or reveal it.
+	if app.contentHidden(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Ref(), http.StatusSeeOther)
+		return
+	}
+
+	to, err := app.queryInt(r, "to")
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if to == 0 {
+		to = snippet.Revision
+	}
+
+	from, err := app.queryInt(r, "from")
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if from == 0 && to > 1 {
+		from = to - 1
+	} else if from == 0 {
+		from = to
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "unified"
+	}
+	if !validator.PermittedValue(mode, "unified", "split") {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	sd, err := app.newSnippetDiff(snippet.ID, from, to)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	sd.Mode = mode
+
+	if raw {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="snippet-%d-r%d-r%d.diff"`, snippet.ID, from, to))
+
+		err = sd.writeUnified(w)
+		if err != nil {
+			app.errorLog.Print(err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Diff = sd
+
+	app.render(w, http.StatusOK, "diff.html", data)
+}
+
+// userSignupForm represents the signup form. The password is never sent back to
+// the browser when the form is re-displayed.
+type userSignupForm struct {
+	Name                string `form:"name"`
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userSignupForm{}
+	app.render(w, http.StatusOK, "signup.html", data)
+}
+
+func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
+	var form userSignupForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Email addresses are compared case-insensitively, so store them lower-cased.
+	form.Email = strings.ToLower(strings.TrimSpace(form.Email))
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank.")
+	form.CheckField(validator.MaxChars(form.Name, 255), "name", "This field cannot be more than 255 characters long.")
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank.")
+	form.CheckField(validator.MaxChars(form.Email, 255), "email", "This field cannot be more than 255 characters long.")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address.")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank.")
+	form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long.")
+	// bcrypt ignores anything after the first 72 bytes of a password.
+	form.CheckField(len(form.Password) <= 72, "password", "This field cannot be more than 72 bytes long.")
+
+	if !form.Valid() {
+		form.Password = ""
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "signup.html", data)
+		return
+	}
+
+	err = app.users.Insert(form.Name, form.Email, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			form.AddFieldError("email", "Email address is already in use.")
+			form.Password = ""
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "signup.html", data)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.addFlash(r, flashSuccess, "Your signup was successful. Please log in.")
+
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+// userLoginForm represents the login form.
+type userLoginForm struct {
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) userLogin(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userLoginForm{}
+	app.render(w, http.StatusOK, "login.html", data)
+}
+
+func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
+	var form userLoginForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.Email = strings.ToLower(strings.TrimSpace(form.Email))
+
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank.")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address.")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank.")
+
+	if form.Valid() {
+		var id int
+
+		id, err = app.users.Authenticate(form.Email, form.Password)
+		if err == nil {
+			err = app.logIn(r, id)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
+			// Send the user back to the page they were trying to reach when
+			// they were asked to log in, if there was one.
+			app.addFlash(r, flashSuccess, "You are now logged in.")
+			http.Redirect(w, r, app.redirectAfterLogin(r), http.StatusSeeOther)
+			return
+		}
+
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			app.serverError(w, err)
+			return
+		}
+
+		// Don't say which of the email address or password was wrong.
+		form.AddNonFieldError("Email or password is incorrect.")
+	}
+
+	form.Password = ""
+	data := app.newTemplateData(r)
+	data.Form = form
+	app.render(w, http.StatusUnprocessableEntity, "login.html", data)
+}
+
+func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
+	err := app.logOut(r)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.addFlash(r, flashSuccess, "You've been logged out successfully!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+func TestSnippetView(t *testing.T) {
+	// Alice owns the snippets, Bob is another user and Carol is an admin.
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+	insertUser(t, app, "Carol", "carol@example.com")
+
+	err := app.users.SetRole("carol@example.com", models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := insertSnippet(t, app, "Public snippet", models.VisibilityPublic, 1)
+	unlisted := insertSnippet(t, app, "Unlisted snippet", models.VisibilityUnlisted, 1)
+	private := insertSnippet(t, app, "Private snippet", models.VisibilityPrivate, 1)
+
+	servers := map[string]*testServer{"": newTestServer(t, app.routes())}
+	for _, email := range []string{"alice@example.com", "bob@example.com", "carol@example.com"} {
+		servers[email] = newTestServer(t, app.routes())
+		servers[email].logIn(t, email, "pa$$word")
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{name: "Public by id", urlPath: "/snippet/view/" + strconv.Itoa(public.ID), wantCode: http.StatusOK, wantBody: "Public snippet"},
+		{name: "Public by slug", urlPath: "/snippet/view/" + public.Slug, wantCode: http.StatusOK, wantBody: "Public snippet"},
+		{name: "Unlisted by slug", urlPath: "/snippet/view/" + unlisted.Slug, wantCode: http.StatusOK, wantBody: "Unlisted snippet"},
+		{name: "Unlisted by id", urlPath: "/snippet/view/" + strconv.Itoa(unlisted.ID), wantCode: http.StatusNotFound},
+		{name: "Unlisted by id, other user", email: "bob@example.com", urlPath: "/snippet/view/" + strconv.Itoa(unlisted.ID), wantCode: http.StatusNotFound},
+		{name: "Unlisted by id, owner", email: "alice@example.com", urlPath: "/snippet/view/" + strconv.Itoa(unlisted.ID), wantCode: http.StatusOK, wantBody: "Unlisted snippet"},
+		{name: "Private by id", urlPath: "/snippet/view/" + strconv.Itoa(private.ID), wantCode: http.StatusNotFound},
+		{name: "Private by slug", urlPath: "/snippet/view/" + private.Slug, wantCode: http.StatusNotFound},
+		{name: "Private, other user", email: "bob@example.com", urlPath: "/snippet/view/" + strconv.Itoa(private.ID), wantCode: http.StatusNotFound},
+		{name: "Private, admin", email: "carol@example.com", urlPath: "/snippet/view/" + strconv.Itoa(private.ID), wantCode: http.StatusNotFound},
+		{name: "Private by id, owner", email: "alice@example.com", urlPath: "/snippet/view/" + strconv.Itoa(private.ID), wantCode: http.StatusOK, wantBody: "Private snippet"},
+		{name: "Private by slug, owner", email: "alice@example.com", urlPath: "/snippet/view/" + private.Slug, wantCode: http.StatusOK, wantBody: "Private snippet"},
+		{name: "Missing id", urlPath: "/snippet/view/99", wantCode: http.StatusNotFound},
+		{name: "Zero id", urlPath: "/snippet/view/0", wantCode: http.StatusNotFound},
+		{name: "Negative id", urlPath: "/snippet/view/-1", wantCode: http.StatusNotFound},
+		{name: "Unknown slug", urlPath: "/snippet/view/AAAAAAAAAAAAAAAAAAAAAA", wantCode: http.StatusNotFound},
+		{name: "Malformed ref", urlPath: "/snippet/view/foo", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := servers[tt.email].get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got body without %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetCreateVisibility(t *testing.T) {
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+
+	ts := newTestServer(t, app.routes())
+	ts.logIn(t, "alice@example.com", "pa$$word")
+
+	tests := []struct {
+		name       string
+		visibility string
+		wantCode   int
+		wantBody   string
+	}{
+		{name: "Public", visibility: models.VisibilityPublic, wantCode: http.StatusSeeOther},
+		{name: "Unlisted", visibility: models.VisibilityUnlisted, wantCode: http.StatusSeeOther},
+		{name: "Private", visibility: models.VisibilityPrivate, wantCode: http.StatusSeeOther},
+		{name: "Unknown", visibility: "secret", wantCode: http.StatusUnprocessableEntity, wantBody: "This field must equal public, unlisted or private."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Hello")
+			form.Add("files[0].content", "Hello, world")
+			form.Add("files[0].language", "auto")
+			form.Add("expires", "1d")
+			form.Add("visibility", tt.visibility)
+			form.Add("format", models.FormatPlain)
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+
+			code, header, body := ts.postForm(t, "/snippet/create", form)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got body without %q", tt.wantBody)
+			}
+			if code != http.StatusSeeOther {
+				return
+			}
+
+			// Unlisted snippets are only found by their slug.
+			location := header.Get("Location")
+			id, err := strconv.Atoi(strings.TrimPrefix(location, "/snippet/view/"))
+			if tt.visibility == models.VisibilityUnlisted {
+				if err == nil {
+					t.Fatalf("got redirect to %q; want a slug", location)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got redirect to %q; want an id", location)
+			}
+
+			snippet, err := app.snippets.Get(id, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snippet.Visibility != tt.visibility {
+				t.Errorf("got visibility %q; want %q", snippet.Visibility, tt.visibility)
+			}
+		})
+	}
+}
+
+func TestSnippetEditVisibility(t *testing.T) {
+	// Alice owns the snippets and Carol is an admin.
+	app := newTestApplication(t)
+	insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Carol", "carol@example.com")
+
+	err := app.users.SetRole("carol@example.com", models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := map[string]*testServer{}
+	for _, email := range []string{"alice@example.com", "carol@example.com"} {
+		servers[email] = newTestServer(t, app.routes())
+		servers[email].logIn(t, email, "pa$$word")
+	}
+
+	tests := []struct {
+		name  string
+		email string
+		from  string
+		to    string
+		// wantLocation is "id" or "slug" for the snippet's own view page.
+		wantLocation string
+	}{
+		{name: "Made unlisted", email: "alice@example.com", from: models.VisibilityPublic, to: models.VisibilityUnlisted, wantLocation: "slug"},
+		{name: "Listed again", email: "alice@example.com", from: models.VisibilityUnlisted, to: models.VisibilityPublic, wantLocation: "id"},
+		{name: "Made private", email: "alice@example.com", from: models.VisibilityPublic, to: models.VisibilityPrivate, wantLocation: "id"},
+		{name: "Made private by admin", email: "carol@example.com", from: models.VisibilityPublic, to: models.VisibilityPrivate, wantLocation: "/"},
+		{name: "Made unlisted by admin", email: "carol@example.com", from: models.VisibilityPrivate, to: models.VisibilityUnlisted, wantLocation: "slug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet := insertSnippet(t, app, "Snippet", tt.from, 1)
+			ts := servers[tt.email]
+
+			form := url.Values{}
+			form.Add("title", "Snippet")
+			form.Add("content", "Content of Snippet")
+			form.Add("language", "auto")
+			form.Add("visibility", tt.to)
+			form.Add("format", models.FormatPlain)
+			form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+			code, header, _ := ts.postForm(t, "/snippet/edit/"+snippet.Ref(), form)
+			if code != http.StatusSeeOther {
+				t.Fatalf("got status %d; want %d", code, http.StatusSeeOther)
+			}
+
+			want := tt.wantLocation
+			switch want {
+			case "id":
+				want = "/snippet/view/" + strconv.Itoa(snippet.ID)
+			case "slug":
+				want = "/snippet/view/" + snippet.Slug
+			}
+			if location := header.Get("Location"); location != want {
+				t.Errorf("got redirect to %q; want %q", location, want)
+			}
+		})
+	}
+}
@@ -6,9 +6,15 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/go-playground/form/v4"
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.sangdennis.com/internal/models"
+	"snippetbox.sangdennis.com/internal/validator"
 )
 
 // the serverError helper writes an error message and stack trace to the errorLog,
@@ -64,13 +70,28 @@ func (app *application) render(w http.ResponseWriter, status int, page string, d
 }
 
 // Create a newTemplateData() helper, which returns a pointer to templateData struct intialized
-// with the current year.
+// with the current year, any flash messages waiting in the session, whether the user is
+// logged in, and the CSRF token for the forms on the page.
 func (app *application) newTemplateData(r *http.Request) *templateData {
 	return &templateData{
-		CurrentYear: time.Now().Year(),
+		CurrentYear:     time.Now().Year(),
+		Flashes:         app.popFlashes(r),
+		IsAuthenticated: app.isAuthenticated(r),
+		CSRFToken:       app.csrfToken(r),
 	}
 }
 
+// newFormDecoder() returns the decoder for the application's forms. The only
+// slices in them are the file entries of the create form, so it won't make a
+// slice of more than maxFiles+1 entries. One too many still decodes, for the
+// validation checks to say there are too many files, but a field named like
+// files[99999].content can't make it allocate a huge slice.
+func newFormDecoder() *form.Decoder {
+	decoder := form.NewDecoder()
+	decoder.SetMaxArraySize(maxFiles + 1)
+	return decoder
+}
+
 // Create a new decodePostForm() helper method. The second parameter here, dst, is the
 // target destination that we want to decode the data into.
 func (app *application) decodePostForm(r *http.Request, dst any) error {
@@ -99,3 +120,98 @@ func (app *application) decodePostForm(r *http.Request, dst any) error {
 	}
 	return nil
 }
+
+// idParam() returns the named URL parameter as a positive integer, such as the
+// id of a snippet. Anything else is an error.
+func (app *application) idParam(r *http.Request, name string) (int, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName(name))
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s parameter %q", name, params.ByName(name))
+	}
+
+	return id, nil
+}
+
+// getSnippet() fetches the snippet which ref points to, as seen by the user
+// making the request. A ref is either a snippet's id or, so that unlisted
+// snippets can be shared, its slug. Anything else gives models.ErrNoRecord.
+func (app *application) getSnippet(r *http.Request, ref string) (*models.Snippet, error) {
+	viewerID := app.authenticatedUserID(r)
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		if id < 1 {
+			return nil, models.ErrNoRecord
+		}
+		return app.snippets.Get(id, viewerID)
+	}
+
+	if !validator.Matches(ref, validator.SlugRX) {
+		return nil, models.ErrNoRecord
+	}
+
+	return app.snippets.GetBySlug(ref, viewerID)
+}
+
+// snippetParam() is getSnippet() for the snippet named by the "id" URL
+// parameter.
+func (app *application) snippetParam(r *http.Request) (*models.Snippet, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	return app.getSnippet(r, params.ByName("id"))
+}
+
+// modifiableSnippetParam() is snippetParam() for the edit and delete pages.
+// Admins may change anybody's snippet, so for them a snippet id finds it
+// whatever its visibility. Everybody else gets the same snippets as from
+// snippetParam(), so that they can't tell somebody else's private snippet from
+// one which doesn't exist.
+func (app *application) modifiableSnippetParam(r *http.Request) (*models.Snippet, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	ref := params.ByName("id")
+
+	if app.isAdmin(r) {
+		if id, err := strconv.Atoi(ref); err == nil && id > 0 {
+			return app.snippets.GetForModify(id)
+		}
+	}
+
+	return app.getSnippet(r, ref)
+}
+
+// queryInt() returns the value of the named query string parameter as an int.
+// A missing or empty parameter is returned as zero; anything else which isn't a
+// non-negative integer is an error.
+func (app *application) queryInt(r *http.Request, key string) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s parameter %q", key, value)
+	}
+
+	return n, nil
+}
+
+// parseTags() splits the comma or space separated tags typed into a form into a
+// list of lower-cased tags, dropping any duplicates.
+func parseTags(input string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range fields {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
@@ -1,42 +1,168 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+	"snippetbox.sangdennis.com/internal/migrations"
 	"snippetbox.sangdennis.com/internal/models"
+	"snippetbox.sangdennis.com/internal/ratelimit"
+	"snippetbox.sangdennis.com/internal/session"
 )
 
 // Define an application struct to hold application-wide dependencies.
 type application struct {
-	errorLog      *log.Logger
-	infoLog       *log.Logger
-	snippets      *models.SnippetModel
-	templateCache map[string]*template.Template
-	formDecoder   *form.Decoder
+	errorLog       *log.Logger
+	infoLog        *log.Logger
+	snippets       models.SnippetStore
+	users          models.UserStore
+	templateCache  map[string]*template.Template
+	formDecoder    *form.Decoder
+	pageSize       int
+	sessionManager *session.Manager
+
+	// maxLifetime is the longest a snippet can be kept, with zero meaning
+	// snippets may also be kept forever.
+	maxLifetime time.Duration
+
+	// Wrong passphrases for protected snippets, counted per snippet and per
+	// client IP address.
+	unlockFailuresBySnippet *ratelimit.Limiter
+	unlockFailuresByIP      *ratelimit.Limiter
 }
 
+// maxPageSize is the most snippets a single page of a listing may hold,
+// whatever page size is configured or requested.
+const maxPageSize = 100
+
+// maxSearchResults is the most snippets a search returns.
+const maxSearchResults = 50
+
+// maxTags is the most tags a snippet can have, and tagCloudSize the number of
+// tags shown in the tag cloud on the home page.
+const (
+	maxTags      = 5
+	tagCloudSize = 20
+)
+
 func main() {
 	addr := flag.String("addr", ":4000", "HTTP network address")
-	dsn := flag.String("dsn", "web:Naingia12@/snippetbox?parseTime=true", "MySQL data soure name")
+	dsn := flag.String("dsn", "web:Naingia12@/snippetbox?parseTime=true", "Data source name (mysql://..., postgres://..., sqlite://... or a bare MySQL DSN)")
+	store := flag.String("store", "sql", "Snippet store to use (sql or memory)")
+	reapInterval := flag.Duration("reap-interval", time.Hour, "How often to purge expired snippets (0 disables)")
+	pageSize := flag.Int("page-size", 10, "Number of snippets per page in listings")
+	reapBatch := flag.Int("reap-batch", 1000, "Maximum number of expired snippets to delete per statement")
+	secret := flag.String("secret", "", "Secret key of at least 32 bytes for signing cookies (random if empty)")
+	sessionLifetime := flag.Duration("session-lifetime", 12*time.Hour, "How long a session lasts at most")
+	sessionIdle := flag.Duration("session-idle", 2*time.Hour, "How long a session lasts without being used (0 disables)")
+	secureCookies := flag.Bool("secure-cookies", false, "Only send the session cookie over HTTPS")
+	maxLifetime := flag.Duration("max-lifetime", 0, "Longest time a snippet can be kept for (0 allows snippets which never expire)")
 
 	flag.Parse()
 
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
-	// pass to openDB the DSN command-line flag
-	db, err := openDB(*dsn)
+	if *pageSize < 1 || *pageSize > maxPageSize {
+		errorLog.Fatalf("-page-size must be between 1 and %d", maxPageSize)
+	}
+
+	if *reapBatch < 1 {
+		errorLog.Fatal("-reap-batch must be at least 1")
+	}
+
+	if *sessionLifetime <= 0 {
+		errorLog.Fatal("-session-lifetime must be positive")
+	}
+
+	if *maxLifetime != 0 && (*maxLifetime < minLifetime || *maxLifetime > absoluteMaxLifetime) {
+		errorLog.Fatalf("-max-lifetime must be 0 or between %s and %s", humanDuration(minLifetime), humanDuration(absoluteMaxLifetime))
+	}
+
+	var snippets models.SnippetStore
+	var users models.UserStore
+	var sessions session.Store
+	var migrator *migrations.Migrator
+
+	switch *store {
+	case "sql":
+		// pass to openDB the DSN command-line flag. The scheme of the DSN decides
+		// which database driver (and so which snippet store) is used.
+		driver, db, err := openDB(*dsn)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		defer db.Close()
+
+		migrator = &migrations.Migrator{DB: db, Dialect: driver}
+
+		// SQLite databases are usually created on the fly by developers and
+		// CI, so create the schema on first run.
+		if driver == "sqlite" && flag.NArg() == 0 {
+			err = migrateFresh(migrator, infoLog)
+			if err != nil {
+				errorLog.Fatal(err)
+			}
+		}
+
+		snippets, users, err = newStores(driver, db)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		sessions = &session.SQLStore{DB: db, Dialect: driver}
+	case "memory":
+		// Boot without a database at all, which is handy for demos.
+		infoLog.Print("Using the in-memory store; snippets, users and sessions will be lost on exit")
+		snippets = &models.MemorySnippetModel{}
+		users = &models.MemoryUserModel{}
+		sessions = &session.MemoryStore{}
+	default:
+		errorLog.Fatalf("unknown store %q (must be sql or memory)", *store)
+	}
+
+	// Any arguments left over after the flags name a command to run instead of
+	// the web server, like "migrate up".
+	if flag.NArg() > 0 {
+		err := runCommand(flag.Args(), infoLog, migrator, snippets, users, *reapBatch)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+
+	// Refuse to start against a database whose schema doesn't match what the
+	// code expects, rather than failing on the first query that touches it.
+	if migrator != nil {
+		pending, err := migrator.Pending()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		if len(pending) > 0 {
+			errorLog.Fatalf("database schema is out of date (%d pending migrations); run the \"migrate up\" command first", len(pending))
+		}
+	}
+
+	secretKey, err := newSecretKey(*secret, infoLog)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
-	defer db.Close()
 
 	// Initialize a new template cache..
 	templateCache, err := newTemplateCache()
@@ -45,15 +171,35 @@ func main() {
 	}
 
 	// Initialize a decoder instance
-	formDecoder := form.NewDecoder()
+	formDecoder := newFormDecoder()
+
+	// Initialize a new session manager, keeping the sessions in the same kind of
+	// store as everything else.
+	sessionManager := session.New(sessions, secretKey)
+	sessionManager.Lifetime = *sessionLifetime
+	sessionManager.IdleTimeout = *sessionIdle
+	sessionManager.Cookie.Name = "snippetbox_session"
+	sessionManager.Cookie.Secure = *secureCookies
 
 	// Initialize a new instance of application struct containing dependencies.
 	app := &application{
-		errorLog:      errorLog,
-		infoLog:       infoLog,
-		snippets:      &models.SnippetModel{DB: db},
-		templateCache: templateCache,
-		formDecoder:   formDecoder,
+		errorLog:       errorLog,
+		infoLog:        infoLog,
+		snippets:       snippets,
+		users:          users,
+		templateCache:  templateCache,
+		formDecoder:    formDecoder,
+		pageSize:       *pageSize,
+		sessionManager: sessionManager,
+		maxLifetime:    *maxLifetime,
+
+		unlockFailuresBySnippet: ratelimit.New(maxUnlockFailuresPerSnippet, unlockWindow),
+		unlockFailuresByIP:      ratelimit.New(maxUnlockFailuresPerIP, unlockWindow),
+	}
+
+	// Report session errors the same way as any other server error.
+	sessionManager.ErrorFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+		app.serverError(w, err)
 	}
 
 	// Initialize a new http.Server struct. Set the Addr and Handler fields so that the
@@ -65,20 +211,162 @@ func main() {
 		Handler:  app.routes(),
 	}
 
+	// Cancel ctx when the process is asked to stop, so the server and any
+	// background workers can shut down cleanly.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	if *reapInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.reapExpired(ctx, *reapInterval, *reapBatch)
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		// Give in-flight requests a few seconds to complete.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			errorLog.Print(err)
+		}
+	}()
+
 	infoLog.Printf("Starting server on %s", *addr)
-	// Call ListenAndServe() method on our new http.Server struct.
+	// Call ListenAndServe() method on our new http.Server struct. After a
+	// graceful shutdown it returns http.ErrServerClosed, which isn't a failure.
 	err = srv.ListenAndServe()
-	errorLog.Fatal(err)
+	if !errors.Is(err, http.ErrServerClosed) {
+		errorLog.Fatal(err)
+	}
+
+	// Wait for the background workers to finish before the deferred db.Close().
+	stop()
+	wg.Wait()
+	infoLog.Print("Server stopped")
+}
+
+// parseDSN() splits a DSN of the form scheme://source into the name of the
+// database/sql driver to use and the driver-specific data source name. A DSN
+// without a scheme is treated as a MySQL DSN, so existing configurations keep
+// working. For example:
+//
+//	sqlite:///var/lib/snippetbox.db -> sqlite, /var/lib/snippetbox.db
+//	mysql://web:pass@/snippetbox    -> mysql, web:pass@/snippetbox
+//	postgres://web:pass@/snippetbox -> postgres, postgres://web:pass@/snippetbox
+//	web:pass@/snippetbox            -> mysql, web:pass@/snippetbox
+func parseDSN(dsn string) (driver string, source string, err error) {
+	scheme, rest, found := strings.Cut(dsn, "://")
+	if !found {
+		return "mysql", dsn, nil
+	}
+
+	switch scheme {
+	case "mysql":
+		return "mysql", rest, nil
+	case "postgres", "postgresql":
+		// The pq driver understands the URL form itself, so pass it on whole.
+		return "postgres", dsn, nil
+	case "sqlite", "sqlite3":
+		if rest == "" {
+			return "", "", fmt.Errorf("missing database path in DSN %q", dsn)
+		}
+		// Enforce foreign keys and wait for locks rather than failing
+		// straight away with SQLITE_BUSY when several connections write.
+		// Transactions take the write lock when they begin, since one which
+		// reads first can't wait for it to be released without deadlocking.
+		sep := "?"
+		if strings.Contains(rest, "?") {
+			sep = "&"
+		}
+		return "sqlite", rest + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate", nil
+	default:
+		return "", "", fmt.Errorf("unsupported DSN scheme %q", scheme)
+	}
 }
 
-// openDB() wraps sql.Open() and returns a sql.DB connection pool for a given DSN.
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+// openDB() wraps sql.Open() and returns the driver name and a sql.DB connection
+// pool for a given DSN.
+func openDB(dsn string) (string, *sql.DB, error) {
+	driver, source, err := parseDSN(dsn)
 	if err != nil {
-		return nil, err
+		return "", nil, err
+	}
+
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		return "", nil, err
 	}
 	if err = db.Ping(); err != nil {
+		db.Close()
+		return "", nil, err
+	}
+	return driver, db, nil
+}
+
+// newStores() returns the SnippetStore and UserStore implementations for the
+// given database driver.
+func newStores(driver string, db *sql.DB) (models.SnippetStore, models.UserStore, error) {
+	switch driver {
+	case "mysql":
+		return &models.SnippetModel{DB: db}, &models.UserModel{DB: db}, nil
+	case "postgres":
+		return &models.PostgresSnippetModel{DB: db}, &models.PostgresUserModel{DB: db}, nil
+	case "sqlite":
+		return &models.SQLiteSnippetModel{DB: db}, &models.SQLiteUserModel{DB: db}, nil
+	default:
+		return nil, nil, fmt.Errorf("no stores for driver %q", driver)
+	}
+}
+
+// newSecretKey() returns the key used to sign cookies. Without a configured key
+// a random one is generated, which means everyone is logged out whenever the
+// server restarts.
+func newSecretKey(secret string, infoLog *log.Logger) ([]byte, error) {
+	if secret != "" {
+		if len(secret) < 32 {
+			return nil, errors.New("-secret must be at least 32 bytes long")
+		}
+		return []byte(secret), nil
+	}
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
 		return nil, err
 	}
-	return db, nil
+
+	infoLog.Print("No -secret given; using a random key, so logins won't survive a restart")
+	return key, nil
+}
+
+// migrateFresh() applies all the migrations to a database which has none
+// applied yet. Databases which have been migrated before are left alone, so
+// that a stale schema is still reported rather than silently upgraded.
+func migrateFresh(migrator *migrations.Migrator, infoLog *log.Logger) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		if s.Applied {
+			return nil
+		}
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		return err
+	}
+
+	infoLog.Printf("Created database schema (%d migrations applied)", len(applied))
+	return nil
 }
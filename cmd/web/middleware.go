@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+
+	"snippetbox.sangdennis.com/internal/models"
 )
 
 func secureHeaders(next http.Handler) http.Handler {
@@ -46,3 +50,106 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// maxRequestBodySize is the largest request body accepted, in bytes. It leaves
+// room for a snippet of maxFiles files of up to 100KB each.
+const maxRequestBodySize = 1 << 20
+
+// limitRequestBody refuses request bodies bigger than maxRequestBodySize, so
+// that a client can't make the server read and decode an endless form. A POST
+// form is read here, before preventCSRF or the handler would, so that one which
+// is too big gets a 413 Request Entity Too Large response of its own.
+func (app *application) limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+		if r.Method == http.MethodPost {
+			err := r.ParseForm()
+			if err != nil {
+				var maxBytesError *http.MaxBytesError
+				if errors.As(err, &maxBytesError) {
+					app.clientError(w, http.StatusRequestEntityTooLarge)
+				} else {
+					app.clientError(w, http.StatusBadRequest)
+				}
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate checks the session on each request, and if a user who still
+// exists is logged in, records that the request is authenticated in the
+// request context.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := app.loggedInUserID(r)
+		if id == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// If the user has been deleted since they logged in, treat the request
+		// as anonymous.
+		user, err := app.users.Get(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				next.ServeHTTP(w, r)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, authenticatedUserIDContextKey, user.ID)
+		ctx = context.WithValue(ctx, isAdminContextKey, user.IsAdmin())
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireAuthentication sends users who aren't logged in to the login page, and
+// remembers the page they wanted so they can be sent back to it afterwards.
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			// Only a GET request can be repeated by redirecting to it. Anything
+			// else (like an expired login submitting a form) goes back to the
+			// home page after logging in.
+			if r.Method == http.MethodGet {
+				app.sessionManager.Put(r.Context(), redirectAfterLoginSessionKey, r.URL.RequestURI())
+			}
+
+			app.addFlash(r, flashWarning, "Please log in to continue.")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+			return
+		}
+
+		// Pages which need a login shouldn't be stored in the browser cache
+		// (or any intermediary cache).
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preventCSRF rejects any request with an unsafe method (like POST) which
+// doesn't pass the checks in validCSRFRequest(), so that other sites can't
+// submit our forms on a logged in user's behalf.
+func (app *application) preventCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		default:
+			if !app.validCSRFRequest(r) {
+				app.forbidden(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
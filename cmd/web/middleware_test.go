@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "Small form", body: "title=" + strings.Repeat("a", 100), wantCode: http.StatusOK},
+		{name: "Largest form", body: "title=" + strings.Repeat("a", maxRequestBodySize-len("title=")), wantCode: http.StatusOK},
+		{name: "Too big", body: "title=" + strings.Repeat("a", maxRequestBodySize), wantCode: http.StatusRequestEntityTooLarge},
+		{name: "Malformed", body: "title=%zz", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var title string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				title = r.PostForm.Get("title")
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+
+			app.limitRequestBody(next).ServeHTTP(rr, r)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", rr.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			values, err := url.ParseQuery(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title != values.Get("title") {
+				t.Errorf("got a title of %d bytes; want %d", len(title), len(values.Get("title")))
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"snippetbox.sangdennis.com/internal/highlight"
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// errInvalidLines is returned by selectLines() for a lines parameter which isn't
+// a line number or range of line numbers within the snippet.
+var errInvalidLines = errors.New("invalid line range")
+
+// selectLines() returns the lines of the content picked by spec, which is a
+// line number like "12" or a range like "12-20", counting from 1. The same forms
+// as in the URL fragments of the snippet's page, "L12" and "L12-L20", work too.
+// Each line keeps its line break, and a range running past the last line stops
+// there.
+func selectLines(content, spec string) (string, error) {
+	from, to, isRange := strings.Cut(spec, "-")
+
+	start, err := strconv.Atoi(strings.TrimPrefix(from, "L"))
+	if err != nil || start < 1 {
+		return "", errInvalidLines
+	}
+
+	end := start
+	if isRange {
+		end, err = strconv.Atoi(strings.TrimPrefix(to, "L"))
+		if err != nil || end < start {
+			return "", errInvalidLines
+		}
+	}
+
+	// Content ending in a line break doesn't have an empty line after it.
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if start > len(lines) {
+		return "", errInvalidLines
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	return strings.Join(lines[start-1:end], ""), nil
+}
+
+// snippetFilename() returns the name one of a snippet's files is downloaded
+// as: its own name if it has one, otherwise the snippet's title with an
+// extension for its format and language, e.g. "deploy-runbook.md".
+func snippetFilename(snippet *models.Snippet, file *models.File) string {
+	if file.Name != "" {
+		return file.Name
+	}
+	return titleSlug(snippet) + fileExtension(snippet.Format, file)
+}
+
+// titleSlug() returns the title of a snippet with each run of anything but
+// letters, digits, hyphens and underscores replaced by a hyphen, to name the
+// files it is downloaded as.
+func titleSlug(snippet *models.Snippet) string {
+	var b strings.Builder
+
+	gap := false
+	for _, r := range snippet.Title {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			gap = true
+			continue
+		}
+		if gap && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		gap = false
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return "snippet-" + strconv.Itoa(snippet.ID)
+	}
+	return b.String()
+}
+
+// fileExtension() returns the file name extension for a file shown in the
+// given format and, for code, its language.
+func fileExtension(format string, file *models.File) string {
+	switch format {
+	case models.FormatMarkdown:
+		return ".md"
+	case models.FormatCode:
+		return highlight.Extension(highlight.Resolve(file.Content, file.Language))
+	default:
+		return ".txt"
+	}
+}
+
+// fileParam() returns the file of the snippet picked by the file query string
+// parameter, counting from 1, or the first file if there is none. It returns
+// models.ErrNoRecord if the snippet has no such file, and another error if the
+// parameter isn't a number.
+func (app *application) fileParam(r *http.Request, snippet *models.Snippet) (*models.File, error) {
+	n, err := app.queryInt(r, "file")
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		n = 1
+	}
+
+	if n > len(snippet.Files) {
+		return nil, models.ErrNoRecord
+	}
+
+	return &snippet.Files[n-1], nil
+}
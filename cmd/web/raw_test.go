@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelectLines(t *testing.T) {
+	const content = "one\ntwo\nthree\nfour\n"
+
+	tests := []struct {
+		name    string
+		content string
+		spec    string
+		want    string
+		wantErr error
+	}{
+		{name: "Single line", content: content, spec: "2", want: "two\n"},
+		{name: "First line", content: content, spec: "1", want: "one\n"},
+		{name: "Last line", content: content, spec: "4", want: "four\n"},
+		{name: "Range", content: content, spec: "2-3", want: "two\nthree\n"},
+		{name: "Range of one line", content: content, spec: "3-3", want: "three\n"},
+		{name: "Fragment style line", content: content, spec: "L2", want: "two\n"},
+		{name: "Fragment style range", content: content, spec: "L2-L3", want: "two\nthree\n"},
+		{name: "Range past the end", content: content, spec: "3-99", want: "three\nfour\n"},
+		{name: "No final line break", content: "one\ntwo", spec: "2", want: "two"},
+		{name: "CRLF line breaks", content: "one\r\ntwo\r\n", spec: "1", want: "one\r\n"},
+		{name: "Reversed range", content: content, spec: "3-2", wantErr: errInvalidLines},
+		{name: "Line zero", content: content, spec: "0", wantErr: errInvalidLines},
+		{name: "Range from zero", content: content, spec: "0-2", wantErr: errInvalidLines},
+		{name: "Negative line", content: content, spec: "-2", wantErr: errInvalidLines},
+		{name: "Line past the end", content: content, spec: "5", wantErr: errInvalidLines},
+		{name: "Range past the end entirely", content: content, spec: "5-9", wantErr: errInvalidLines},
+		{name: "Empty content", content: "", spec: "1", wantErr: errInvalidLines},
+		{name: "Empty spec", content: content, spec: "", wantErr: errInvalidLines},
+		{name: "Not a number", content: content, spec: "two", wantErr: errInvalidLines},
+		{name: "Open range", content: content, spec: "2-", wantErr: errInvalidLines},
+		{name: "Three parts", content: content, spec: "1-2-3", wantErr: errInvalidLines},
+		{name: "Spaces", content: content, spec: " 2", wantErr: errInvalidLines},
+		{name: "Wrong prefix", content: content, spec: "F2", wantErr: errInvalidLines},
+		{name: "Huge number", content: content, spec: "99999999999999999999", wantErr: errInvalidLines},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectLines(tt.content, tt.spec)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// purgeExpired() deletes expired snippets in batches of batchSize until there
+// are none left, and returns the total number deleted. Deleting in batches keeps
+// each statement short, so it doesn't hold locks on the table for long.
+func purgeExpired(snippets models.SnippetStore, batchSize int) (int, error) {
+	total := 0
+
+	for {
+		n, err := snippets.DeleteExpired(batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		// A short batch means there's nothing left to delete.
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// reapExpired() purges expired snippets and sessions every interval until ctx is
+// cancelled.
+// It is meant to be run in its own goroutine.
+func (app *application) reapExpired(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := purgeExpired(app.snippets, batchSize)
+			if err != nil {
+				app.errorLog.Printf("purging expired snippets: %s", err)
+			}
+			if n > 0 {
+				app.infoLog.Printf("Purged %d expired snippets", n)
+			}
+
+			n, err = app.sessionManager.Store.DeleteExpired()
+			if err != nil {
+				app.errorLog.Printf("purging expired sessions: %s", err)
+			}
+			if n > 0 {
+				app.infoLog.Printf("Purged %d expired sessions", n)
+			}
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
 	"path/filepath"
 	"time"
 
+	"snippetbox.sangdennis.com/internal/highlight"
+	"snippetbox.sangdennis.com/internal/markdown"
 	"snippetbox.sangdennis.com/internal/models"
+	"snippetbox.sangdennis.com/internal/search"
 )
 
 // Define a templateData type to act as the holding structure for
@@ -13,10 +18,21 @@ import (
 // At the moment it only contains one field, but we'll add more
 // to it as the build progresses.
 type templateData struct {
-	CurrentYear int
-	Snippet     *models.Snippet
-	Snippets    []*models.Snippet
-	Form        any
+	CurrentYear     int
+	Snippet         *models.Snippet
+	Snippets        []*models.Snippet
+	Page            *models.SnippetPage
+	Form            any
+	Query           string
+	Tag             string
+	Tags            []*models.TagCount
+	Revision        *models.Revision
+	Revisions       []*models.Revision
+	Diff            *snippetDiff
+	Flashes         []flash
+	IsAuthenticated bool
+	CSRFToken       string
+	CanModify       bool
 }
 
 // Create humanDate() which returns a nicely formatted string representation
@@ -25,11 +41,107 @@ func humanDate(t time.Time) string {
 	return t.Format("02 Jan 2006 at 15:04")
 }
 
+// humanDuration() formats a duration in the largest whole unit it divides into,
+// e.g. "7 days" or "90 minutes", falling back to the Go syntax.
+func humanDuration(d time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, u := range units {
+		if d >= u.size && d%u.size == 0 {
+			n := int64(d / u.size)
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+	}
+
+	return d.String()
+}
+
+// tagWeight() returns a weight from 1 to 5 for a tag in the tag cloud, in
+// proportion to how often it is used compared to the most used tag. The weight
+// picks a CSS class, because inline styles are blocked by our CSP.
+func tagWeight(tag *models.TagCount, tags []*models.TagCount) int {
+	max := 1
+	for _, t := range tags {
+		if t.Count > max {
+			max = t.Count
+		}
+	}
+
+	if max == 1 {
+		return 1
+	}
+
+	return 1 + (tag.Count-1)*4/(max-1)
+}
+
 // Initialize a template.FuncMap object and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup between the names of our
 // custome template functions and the functions themselves.
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":        humanDate,
+	"humanDuration":    humanDuration,
+	"highlight":        search.Highlight,
+	"excerpt":          excerpt,
+	"tagWeight":        tagWeight,
+	"dict":             dict,
+	"permittedPresets": permittedPresets,
+	"burnLifetime":     burnLifetime,
+	"languages":        languages,
+	"resolveLanguage":  highlight.Resolve,
+	"languageLabel":    highlight.Label,
+	"highlightCode":    highlight.HTML,
+	"renderMarkdown":   markdown.Render,
+	"filePrefix":       filePrefix,
+}
+
+// filePrefix() returns what the ids of the lines and headings of a snippet's
+// file start with: nothing for the first file, so that links to the lines of
+// snippets with only one file look like #L12, and "F2-" and so on for the rest.
+func filePrefix(number int) string {
+	if number <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("F%d-", number)
+}
+
+// languages() returns the languages offered on the snippet forms.
+func languages() []highlight.Language {
+	return highlight.Languages
+}
+
+// dict() builds a map from alternating keys and values, so that a template can
+// pass several values to another template it invokes.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict needs an even number of arguments")
+	}
+
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+
+	return m, nil
+}
+
+// excerpt() returns a short extract of text around the first match of the
+// search query, for showing in the search results.
+func excerpt(text, query string) string {
+	return search.Excerpt(text, query, 160)
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
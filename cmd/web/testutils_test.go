@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/highlight"
+	"snippetbox.sangdennis.com/internal/models"
+	"snippetbox.sangdennis.com/internal/ratelimit"
+	"snippetbox.sangdennis.com/internal/session"
+)
+
+// TestMain() runs the tests from the root of the repository, as the server is,
+// so that the templates and static files are found.
+func TestMain(m *testing.M) {
+	err := os.Chdir("../..")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newTestApplication() returns an application backed by the in-memory stores,
+// which doesn't log anything.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionManager := session.New(&session.MemoryStore{}, []byte("a secret key of at least 32 bytes!"))
+
+	app := &application{
+		errorLog:       log.New(io.Discard, "", 0),
+		infoLog:        log.New(io.Discard, "", 0),
+		snippets:       &models.MemorySnippetModel{},
+		users:          &models.MemoryUserModel{},
+		templateCache:  templateCache,
+		formDecoder:    newFormDecoder(),
+		pageSize:       10,
+		sessionManager: sessionManager,
+
+		unlockFailuresBySnippet: ratelimit.New(maxUnlockFailuresPerSnippet, unlockWindow),
+		unlockFailuresByIP:      ratelimit.New(maxUnlockFailuresPerIP, unlockWindow),
+	}
+
+	sessionManager.ErrorFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+		app.serverError(w, err)
+	}
+
+	return app
+}
+
+// testServer is a test HTTP server running the application's routes, with a
+// client which keeps cookies, like a browser, but doesn't follow redirects.
+type testServer struct {
+	*httptest.Server
+}
+
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	t.Helper()
+
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.Client().Jar = jar
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &testServer{ts}
+}
+
+// get() sends a GET request for the path, and returns the status code, headers
+// and body of the response.
+func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, string) {
+	t.Helper()
+
+	rs, err := ts.Client().Get(ts.URL + urlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return readResponse(t, rs)
+}
+
+// postForm() sends a POST request for the path with the form values, and
+// returns the status code, headers and body of the response.
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, string) {
+	t.Helper()
+
+	rs, err := ts.Client().PostForm(ts.URL+urlPath, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return readResponse(t, rs)
+}
+
+func readResponse(t *testing.T, rs *http.Response) (int, http.Header, string) {
+	t.Helper()
+
+	defer rs.Body.Close()
+
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(body))
+}
+
+var csrfTokenRX = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="(.+?)">`)
+
+// csrfToken() fetches the page at the path and returns the CSRF token from its
+// form.
+func (ts *testServer) csrfToken(t *testing.T, urlPath string) string {
+	t.Helper()
+
+	_, _, body := ts.get(t, urlPath)
+
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatalf("no CSRF token found in %s", urlPath)
+	}
+
+	return html.UnescapeString(matches[1])
+}
+
+// logIn() logs the test server's client in with the email address and
+// password.
+func (ts *testServer) logIn(t *testing.T, email, password string) {
+	t.Helper()
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", password)
+	form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("logging in as %s: got status %d; want %d", email, code, http.StatusSeeOther)
+	}
+}
+
+// insertUser() adds a user with the password "pa$$word" to the application's
+// store. The memory store numbers users in order from 1.
+func insertUser(t *testing.T, app *application, name, email string) {
+	t.Helper()
+
+	err := app.users.Insert(name, email, "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// insertSnippet() adds a snippet with the title and visibility, owned by the
+// user with ownerID, and returns it.
+func insertSnippet(t *testing.T, app *application, title, visibility string, ownerID int) *models.Snippet {
+	t.Helper()
+
+	id, err := app.snippets.Insert(models.NewSnippet{
+		Title:      title,
+		Files:      []models.File{{Language: highlight.Auto, Content: "Content of " + title}},
+		Lifetime:   time.Hour,
+		OwnerID:    ownerID,
+		Visibility: visibility,
+		Format:     models.FormatPlain,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snippet, err := app.snippets.Get(id, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return snippet
+}
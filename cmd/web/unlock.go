@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+// unlockedSnippetsSessionKey is the session key holding the ids of the
+// passphrase-protected snippets the user has unlocked.
+const unlockedSnippetsSessionKey = "unlockedSnippets"
+
+// Wrong passphrases are limited per snippet, which stops guessing from many
+// addresses at once, and more tightly per client IP address, which stops one
+// client guessing at many snippets. Both count failures over unlockWindow.
+const (
+	unlockWindow                = 15 * time.Minute
+	maxUnlockFailuresPerSnippet = 20
+	maxUnlockFailuresPerIP      = 5
+)
+
+// isUnlocked() returns true if the user may see the content of the snippet:
+// either it has no passphrase, it belongs to them, or they have already given
+// the passphrase in this session.
+func (app *application) isUnlocked(r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.Protected || snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+
+	ids, _ := app.sessionManager.Get(r.Context(), unlockedSnippetsSessionKey).([]int)
+	for _, id := range ids {
+		if id == snippet.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markUnlocked() records in the session that the user has given the passphrase
+// for the snippet with the given id.
+func (app *application) markUnlocked(r *http.Request, id int) {
+	ids, _ := app.sessionManager.Get(r.Context(), unlockedSnippetsSessionKey).([]int)
+	app.sessionManager.Put(r.Context(), unlockedSnippetsSessionKey, append(ids, id))
+}
+
+// attemptUnlock() counts a passphrase attempt against both the client and the
+// snippet, if neither has used up its failures. If one has, nothing is counted
+// and it returns how long the client must wait. The attempt is counted before
+// the passphrase is checked, so that parallel requests can't all get past the
+// limits; refundUnlock() takes it back if it wasn't a wrong passphrase.
+func (app *application) attemptUnlock(r *http.Request, snippet *models.Snippet) (bool, time.Duration) {
+	ip := clientIP(r)
+
+	ok, wait := app.unlockFailuresByIP.Attempt(ip)
+	if !ok {
+		return false, wait
+	}
+
+	ok, wait = app.unlockFailuresBySnippet.Attempt(strconv.Itoa(snippet.ID))
+	if !ok {
+		app.unlockFailuresByIP.Refund(ip)
+		return false, wait
+	}
+
+	return true, 0
+}
+
+// refundUnlock() takes back an attempt counted by attemptUnlock().
+func (app *application) refundUnlock(r *http.Request, snippet *models.Snippet) {
+	app.unlockFailuresByIP.Refund(clientIP(r))
+	app.unlockFailuresBySnippet.Refund(strconv.Itoa(snippet.ID))
+}
+
+// clientIP() returns the IP address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
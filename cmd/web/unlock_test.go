@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/highlight"
+	"snippetbox.sangdennis.com/internal/models"
+)
+
+func TestSnippetUnlockConcurrent(t *testing.T) {
+	app := newTestApplication(t)
+
+	id, err := app.snippets.Insert(models.NewSnippet{
+		Title:      "Protected",
+		Files:      []models.File{{Language: highlight.Auto, Content: "Secret"}},
+		Lifetime:   time.Hour,
+		Visibility: models.VisibilityPublic,
+		Format:     models.FormatPlain,
+		Passphrase: "open sesame",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	form := url.Values{}
+	form.Add("passphrase", "wrong")
+	form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+	// Guess more times than allowed all at once. Each guess is counted before
+	// the passphrase is checked, so only the allowed number get checked and
+	// the rest are turned away.
+	const requests = maxUnlockFailuresPerIP + 7
+
+	var wg sync.WaitGroup
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rs, err := ts.Client().PostForm(ts.URL+"/snippet/unlock/"+strconv.Itoa(id), form)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rs.Body.Close()
+			codes <- rs.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+
+	if counts[http.StatusUnprocessableEntity] != maxUnlockFailuresPerIP {
+		t.Errorf("got %d wrong passphrases checked; want %d", counts[http.StatusUnprocessableEntity], maxUnlockFailuresPerIP)
+	}
+	if counts[http.StatusTooManyRequests] != requests-maxUnlockFailuresPerIP {
+		t.Errorf("got %d requests turned away; want %d", counts[http.StatusTooManyRequests], requests-maxUnlockFailuresPerIP)
+	}
+}
+
+func TestSnippetUnlockRefund(t *testing.T) {
+	app := newTestApplication(t)
+
+	id, err := app.snippets.Insert(models.NewSnippet{
+		Title:      "Protected",
+		Files:      []models.File{{Language: highlight.Auto, Content: "Secret"}},
+		Lifetime:   time.Hour,
+		Visibility: models.VisibilityPublic,
+		Format:     models.FormatPlain,
+		Passphrase: "open sesame",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+	token := ts.csrfToken(t, "/user/login")
+
+	// Blank passphrases aren't guesses, so they don't count as failures.
+	for i := 0; i < maxUnlockFailuresPerIP+1; i++ {
+		code, _, _ := ts.postForm(t, "/snippet/unlock/"+strconv.Itoa(id), url.Values{"passphrase": {""}, "csrf_token": {token}})
+		if code != http.StatusUnprocessableEntity {
+			t.Fatalf("got status %d for blank passphrase %d; want %d", code, i+1, http.StatusUnprocessableEntity)
+		}
+	}
+
+	code, _, _ := ts.postForm(t, "/snippet/unlock/"+strconv.Itoa(id), url.Values{"passphrase": {"open sesame"}, "csrf_token": {token}})
+	if code != http.StatusSeeOther {
+		t.Errorf("got status %d for the right passphrase; want %d", code, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,441 @@
+// Package diff computes line-based differences between two texts, using the
+// Myers algorithm, and formats them as unified diffs or as the rows of a
+// side-by-side view.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxEdits caps the number of inserted plus deleted lines the Myers search will
+// look for. The search takes time proportional to the length of the texts times
+// the number of edits, so texts which differ by more than this are diffed as a
+// wholesale replacement instead.
+const MaxEdits = 2000
+
+// Op says what happened to a line going from the old text to the new one.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// String() returns the name of the operation, which is also used as a CSS class.
+func (op Op) String() string {
+	switch op {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "equal"
+	}
+}
+
+// Line is one line of a diff. OldNumber and NewNumber are the 1-based line
+// numbers in the old and new texts, or zero for inserted and deleted lines
+// respectively.
+type Line struct {
+	Op        Op
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+// Marker() returns the character that prefixes the line in a unified diff.
+func (l Line) Marker() string {
+	switch l.Op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// SplitLines() splits text into lines, treating \r\n and \n alike. A final line
+// break doesn't start a new, empty line.
+func SplitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+// Lines() returns the diff between the old and new lines, as every line of both
+// texts in order, each marked as equal, deleted or inserted.
+func Lines(old, new []string) []Line {
+	ops := myers(old, new)
+
+	lines := make([]Line, 0, len(ops))
+	o, n := 0, 0
+
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			lines = append(lines, Line{Op: Equal, Text: old[o], OldNumber: o + 1, NewNumber: n + 1})
+			o++
+			n++
+		case Delete:
+			lines = append(lines, Line{Op: Delete, Text: old[o], OldNumber: o + 1})
+			o++
+		case Insert:
+			lines = append(lines, Line{Op: Insert, Text: new[n], NewNumber: n + 1})
+			n++
+		}
+	}
+
+	return lines
+}
+
+// myers() returns the shortest edit script turning a into b, as one Op for each
+// line of output.
+//
+// It uses the linear space variant of the Myers algorithm: rather than keeping
+// every round of the search to backtrack through, it searches forwards from the
+// start and backwards from the end at the same time until the two meet in the
+// middle of the shortest path, then does the same for the halves either side of
+// that point. That needs memory proportional to the length of the texts, however
+// many edits there are.
+func myers(a, b []string) []Op {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	s := &myersSearch{a: a, b: b, ops: make([]Op, 0, len(a)+len(b))}
+	s.compare(0, len(a), 0, len(b))
+	return s.ops
+}
+
+// myersSearch holds the state of a call to myers(): the texts, and the ops
+// found so far, which compare() appends to in order.
+type myersSearch struct {
+	a, b []string
+	ops  []Op
+}
+
+// compare() appends the ops turning a[aLo:aHi] into b[bLo:bHi].
+func (s *myersSearch) compare(aLo, aHi, bLo, bHi int) {
+	// Lines matching at the start or the end are always part of a shortest
+	// path, so take them off before searching.
+	for aLo < aHi && bLo < bHi && s.a[aLo] == s.b[bLo] {
+		s.ops = append(s.ops, Equal)
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi && bLo < bHi && s.a[aHi-1] == s.b[bHi-1] {
+		aHi--
+		bHi--
+		suffix++
+	}
+
+	switch {
+	case aLo == aHi:
+		s.repeat(Insert, bHi-bLo)
+	case bLo == bHi:
+		s.repeat(Delete, aHi-aLo)
+	default:
+		x, y, ok := s.middle(aLo, aHi, bLo, bHi)
+		if ok {
+			s.compare(aLo, x, bLo, y)
+			s.compare(x, aHi, y, bHi)
+		} else {
+			s.repeat(Delete, aHi-aLo)
+			s.repeat(Insert, bHi-bLo)
+		}
+	}
+
+	s.repeat(Equal, suffix)
+}
+
+// repeat() appends n copies of op.
+func (s *myersSearch) repeat(op Op, n int) {
+	for i := 0; i < n; i++ {
+		s.ops = append(s.ops, op)
+	}
+}
+
+// middle() finds a point (x, y) on a shortest path turning a[aLo:aHi] into
+// b[bLo:bHi], splitting it into two parts with about half the edits each. It
+// returns false if the path needs more than MaxEdits edits.
+func (s *myersSearch) middle(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+
+	maxD := (n + m + 1) / 2
+	if limit := MaxEdits/2 + 1; maxD > limit {
+		maxD = limit
+	}
+
+	// forward[offset+k] holds the furthest x reached from the start on
+	// diagonal k (where k = x - y), and backward[offset+k] the same for the
+	// search from the end, with x and y counted back from the end. -1 marks a
+	// diagonal not reached yet.
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	// Diagonal k of one search is diagonal delta-k of the other. When delta
+	// is odd the searches can only meet during a forward round, otherwise
+	// during a backward one.
+	delta := n - m
+	odd := delta%2 != 0
+
+	// Diagonals whose path has run off the edge of the grid are left out of
+	// later rounds, by narrowing the range of k at that end.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1] // move down: an insertion
+			} else {
+				x = forward[offset+k-1] + 1 // move right: a deletion
+			}
+			y := x - k
+
+			// Follow the diagonal while the lines match.
+			for x < n && y < m && s.a[aLo+x] == s.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				back := offset + delta - k
+				if back >= 0 && back < len(backward) && backward[back] != -1 && x >= n-backward[back] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && s.a[aHi-x-1] == s.b[bHi-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				fwd := offset + delta - k
+				if fwd >= 0 && fwd < len(forward) && forward[fwd] != -1 {
+					fx := forward[fwd]
+					if fx >= n-x {
+						return aLo + fx, bLo + fx - (fwd - offset), true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// replaceAll() returns an edit script which deletes every line of a and then
+// inserts every line of b.
+func replaceAll(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+	for range a {
+		ops = append(ops, Delete)
+	}
+	for range b {
+		ops = append(ops, Insert)
+	}
+	return ops
+}
+
+// Hunk is a run of changed lines along with the unchanged lines around them.
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Lines    []Line
+}
+
+// Header() returns the hunk's @@ -a,b +c,d @@ range line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+}
+
+// Hunks() groups the changed lines into hunks, each with up to context lines of
+// unchanged text either side. Changes close enough for their context to touch
+// share a hunk. If nothing changed there are no hunks.
+func Hunks(lines []Line, context int) []Hunk {
+	hunks := []Hunk{}
+
+	i := 0
+	for i < len(lines) {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Find the end of this group of changes: keep going while the next
+		// change is within 2*context unchanged lines of the last.
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Op != Equal {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+
+		stop := end + context + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		hunks = append(hunks, newHunk(lines, start, stop))
+		i = stop
+	}
+
+	return hunks
+}
+
+// newHunk() builds the hunk covering lines[start:stop].
+func newHunk(lines []Line, start, stop int) Hunk {
+	h := Hunk{Lines: lines[start:stop]}
+
+	// Count the old and new lines before the hunk to find where it starts.
+	for _, l := range lines[:start] {
+		if l.Op != Insert {
+			h.OldStart++
+		}
+		if l.Op != Delete {
+			h.NewStart++
+		}
+	}
+
+	for _, l := range h.Lines {
+		if l.Op != Insert {
+			h.OldCount++
+		}
+		if l.Op != Delete {
+			h.NewCount++
+		}
+	}
+
+	// By convention an empty range starts at the line before it.
+	if h.OldCount > 0 {
+		h.OldStart++
+	}
+	if h.NewCount > 0 {
+		h.NewStart++
+	}
+
+	return h
+}
+
+// Row is one row of a side-by-side diff. Left is a line from the old text and
+// Right from the new; either is nil where the other side has no counterpart.
+type Row struct {
+	Left  *Line
+	Right *Line
+}
+
+// Rows() lays the hunk's lines out side by side. Unchanged lines appear on both
+// sides, and each run of deleted lines is paired up with the run of inserted
+// lines which follows it.
+func (h Hunk) Rows() []Row {
+	rows := []Row{}
+	lines := h.Lines
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			rows = append(rows, Row{Left: &lines[i], Right: &lines[i]})
+			i++
+			continue
+		}
+
+		deleted := []*Line{}
+		for ; i < len(lines) && lines[i].Op == Delete; i++ {
+			deleted = append(deleted, &lines[i])
+		}
+
+		inserted := []*Line{}
+		for ; i < len(lines) && lines[i].Op == Insert; i++ {
+			inserted = append(inserted, &lines[i])
+		}
+
+		for j := 0; j < len(deleted) || j < len(inserted); j++ {
+			row := Row{}
+			if j < len(deleted) {
+				row.Left = deleted[j]
+			}
+			if j < len(inserted) {
+				row.Right = inserted[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// WriteUnified() writes the hunks to w as a unified diff, with oldName and
+// newName in the --- and +++ header lines. Nothing is written if there are no
+// hunks.
+func WriteUnified(w io.Writer, oldName, newName string, hunks []Hunk) error {
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range hunks {
+		_, err = fmt.Fprintln(w, h.Header())
+		if err != nil {
+			return err
+		}
+
+		for _, l := range h.Lines {
+			_, err = fmt.Fprintf(w, "%s%s\n", l.Marker(), l.Text)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcsLength() returns the length of the longest common subsequence of a and b,
+// by dynamic programming. A shortest edit script deletes every other line of a
+// and inserts every other line of b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				cur[j] = prev[j-1] + 1
+			case prev[j] > cur[j-1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// checkLines() checks that the diff lines rebuild both texts with the right
+// line numbers, and returns the number of edits.
+func checkLines(t *testing.T, old, new []string, lines []Line) int {
+	t.Helper()
+
+	var gotOld, gotNew []string
+	edits := 0
+
+	for _, l := range lines {
+		if l.Op != Insert {
+			gotOld = append(gotOld, l.Text)
+			if l.OldNumber != len(gotOld) {
+				t.Errorf("line %q: got old number %d; want %d", l.Text, l.OldNumber, len(gotOld))
+			}
+		}
+		if l.Op != Delete {
+			gotNew = append(gotNew, l.Text)
+			if l.NewNumber != len(gotNew) {
+				t.Errorf("line %q: got new number %d; want %d", l.Text, l.NewNumber, len(gotNew))
+			}
+		}
+		if l.Op != Equal {
+			edits++
+		}
+	}
+
+	if strings.Join(gotOld, "\n") != strings.Join(old, "\n") {
+		t.Errorf("got old text %q; want %q", gotOld, old)
+	}
+	if strings.Join(gotNew, "\n") != strings.Join(new, "\n") {
+		t.Errorf("got new text %q; want %q", gotNew, new)
+	}
+
+	return edits
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string
+		new   string
+		edits int
+	}{
+		{name: "Both empty", old: "", new: "", edits: 0},
+		{name: "Identical", old: "a\nb\nc", new: "a\nb\nc", edits: 0},
+		{name: "All inserted", old: "", new: "a\nb", edits: 2},
+		{name: "All deleted", old: "a\nb", new: "", edits: 2},
+		{name: "Nothing in common", old: "a\nb", new: "c\nd", edits: 4},
+		{name: "Changed line", old: "a\nb\nc", new: "a\nx\nc", edits: 2},
+		{name: "Inserted in middle", old: "a\nc", new: "a\nb\nc", edits: 1},
+		{name: "Moved line", old: "a\nb\nc\nd", new: "b\nc\nd\na", edits: 2},
+		{name: "Myers paper", old: "a\nb\nc\na\nb\nb\na", new: "c\nb\na\nb\na\nc", edits: 5},
+		{name: "Repeated lines", old: "x\nx\nx\ny", new: "y\nx\nx\nx", edits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := SplitLines(tt.old), SplitLines(tt.new)
+
+			edits := checkLines(t, old, new, Lines(old, new))
+			if edits != tt.edits {
+				t.Errorf("got %d edits; want %d", edits, tt.edits)
+			}
+		})
+	}
+}
+
+func TestLinesMinimal(t *testing.T) {
+	// Diff random texts over a small alphabet, which have plenty of repeated
+	// lines, and check the number of edits against the longest common
+	// subsequence.
+	rng := rand.New(rand.NewSource(1))
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		old, new := randomLines(), randomLines()
+
+		edits := checkLines(t, old, new, Lines(old, new))
+		want := len(old) + len(new) - 2*lcsLength(old, new)
+		if edits != want {
+			t.Fatalf("diffing %q and %q: got %d edits; want %d", old, new, edits, want)
+		}
+	}
+}
+
+func TestLinesMaxEdits(t *testing.T) {
+	// Texts which differ by more than MaxEdits lines are diffed as a
+	// wholesale replacement, apart from the lines they start and end with.
+	old := []string{"first"}
+	new := []string{"first"}
+	for i := 0; i < MaxEdits; i++ {
+		old = append(old, "old")
+		new = append(new, "new")
+	}
+	old = append(old, "last")
+	new = append(new, "last")
+
+	lines := Lines(old, new)
+
+	edits := checkLines(t, old, new, lines)
+	if edits != 2*MaxEdits {
+		t.Errorf("got %d edits; want %d", edits, 2*MaxEdits)
+	}
+	if lines[0].Op != Equal || lines[len(lines)-1].Op != Equal {
+		t.Errorf("got first and last lines %v and %v; want equal", lines[0].Op, lines[len(lines)-1].Op)
+	}
+}
+
+func TestHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		context int
+		headers []string
+	}{
+		{name: "No changes", old: "a\nb", new: "a\nb", context: 3, headers: []string{}},
+		{name: "One change", old: "a\nb\nc", new: "a\nx\nc", context: 1, headers: []string{"@@ -1,3 +1,3 @@"}},
+		{name: "Separate changes", old: "a\nb\nc\nd\ne\nf\ng", new: "x\nb\nc\nd\ne\nf\ny", context: 1,
+			headers: []string{"@@ -1,2 +1,2 @@", "@@ -6,2 +6,2 @@"}},
+		{name: "Touching context", old: "a\nb\nc\nd", new: "x\nb\nc\ny", context: 1, headers: []string{"@@ -1,4 +1,4 @@"}},
+		{name: "Insert into empty", old: "", new: "a", context: 3, headers: []string{"@@ -0,0 +1,1 @@"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Hunks(Lines(SplitLines(tt.old), SplitLines(tt.new)), tt.context)
+
+			headers := []string{}
+			for _, h := range hunks {
+				headers = append(headers, h.Header())
+			}
+
+			if strings.Join(headers, " ") != strings.Join(tt.headers, " ") {
+				t.Errorf("got %q; want %q", headers, tt.headers)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package highlight
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// detectBytes is how much of the content Detect() looks at. The start of a
+// snippet is usually enough to tell what it is.
+const detectBytes = 16 * 1024
+
+// minScore is the score a language needs before Detect() picks it, so that a
+// single weak clue, like a line starting with "import", isn't enough.
+const minScore = 3
+
+// clue is a pattern which suggests the content is in a language. The weight
+// is how strongly it suggests so: 3 or more is enough on its own.
+type clue struct {
+	language string
+	pattern  *regexp.Regexp
+	weight   int
+}
+
+// clues are matched against the start of the content, with ^ and $ matching at
+// line breaks. Chroma can also guess languages, but only has guessers for a few of
+// the ones we offer, and those it has often mistake one language for another.
+var clues = []clue{
+	{"bash", regexp.MustCompile(`(?m)\A#!.*\b(ba|z)?sh\b`), 5},
+	{"bash", regexp.MustCompile(`(?m)^\s*(echo|export|sudo|apt(-get)?|cd|chmod|mkdir) `), 1},
+	{"bash", regexp.MustCompile(`(?m)^\s*(if \[\[? |fi$|done$|esac$)`), 2},
+
+	{"c", regexp.MustCompile(`(?m)^#include\s*[<"]`), 3},
+	{"c", regexp.MustCompile(`(?m)\b(printf|malloc|free)\(|\bint main\(`), 1},
+	{"cpp", regexp.MustCompile(`(?m)\bstd::|^#include\s*<(iostream|vector|string|map|memory)>|\btemplate\s*<|\bnamespace \w+\s*\{`), 4},
+	{"cpp", regexp.MustCompile(`(?m)\bcout\s*<<|\bcin\s*>>`), 2},
+
+	{"csharp", regexp.MustCompile(`(?m)^using System(\.[\w.]+)?;|\bConsole\.Write(Line)?\(`), 5},
+	{"csharp", regexp.MustCompile(`(?m)\bnamespace [\w.]+|\{ get; (private )?set; \}`), 2},
+
+	{"css", regexp.MustCompile(`(?m)^\s*[.#@]?[\w-][\w\s.#:,>~+\[\]="'-]*\{\s*$`), 1},
+	{"css", regexp.MustCompile(`(?m)^\s*(color|background(-color)?|margin|padding|font(-\w+)?|display|border|width|height)\s*:[^;]+;\s*$`), 2},
+
+	{"diff", regexp.MustCompile(`(?m)^diff --git `), 5},
+	{"diff", regexp.MustCompile(`(?m)^--- .*\n\+\+\+ `), 4},
+	{"diff", regexp.MustCompile(`(?m)^@@ -\d+(,\d+)? \+\d+(,\d+)? @@`), 3},
+
+	{"dockerfile", regexp.MustCompile(`(?m)\A(#.*\n|\s*\n)*FROM \S+`), 3},
+	{"dockerfile", regexp.MustCompile(`(?m)^(RUN|CMD|COPY|ENTRYPOINT|WORKDIR|EXPOSE) `), 2},
+
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$`), 5},
+	{"go", regexp.MustCompile(`(?m)^func (\(\w+ \*?\w+\) )?\w+\(|\bfmt\.\w+\(|\w+ := `), 2},
+	{"go", regexp.MustCompile(`(?m)\bif err != nil \{`), 3},
+
+	{"html", regexp.MustCompile(`(?mi)\A\s*<!doctype html|<html[\s>]`), 5},
+	{"html", regexp.MustCompile(`(?m)</(div|p|span|a|body|head|ul|li|table|form|script)>`), 3},
+
+	{"java", regexp.MustCompile(`(?m)\bSystem\.out\.print(ln)?\(|^import java\.|\bpublic static void main\(String`), 5},
+	{"java", regexp.MustCompile(`(?m)^\s*(public|private|protected) (static )?(final )?\w+(<[\w, <>]+>)? \w+\(`), 1},
+
+	{"javascript", regexp.MustCompile(`(?m)\bconsole\.log\(|\bdocument\.\w+|\brequire\(['"]|\bmodule\.exports\b`), 3},
+	{"javascript", regexp.MustCompile(`(?m)\bfunction\s*\w*\(|^import .* from ['"]|^export (default )?(function|const|class) `), 2},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+ = |=> `), 1},
+
+	{"kotlin", regexp.MustCompile(`(?m)^\s*fun \w+\(`), 3},
+	{"kotlin", regexp.MustCompile(`(?m)^\s*(val|var) \w+(: \w+)? = |\bprintln\(`), 1},
+
+	{"markdown", regexp.MustCompile(`(?m)^#{1,6} \S`), 2},
+	{"markdown", regexp.MustCompile("(?m)^```|\\[[^\\]]+\\]\\([^)]+\\)|^\\s*[-*] \\S|^\\d+\\. \\S|\\*\\*\\S"), 1},
+
+	{"php", regexp.MustCompile(`(?m)<\?php`), 6},
+	{"php", regexp.MustCompile(`(?m)\$\w+\s*=|\becho\s+\$|->\w+\(`), 1},
+
+	{"python", regexp.MustCompile(`(?m)\A#!.*\bpython`), 5},
+	{"python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\)(\s*->\s*[\w\[\], .]+)?:\s*$|^\s*class \w+(\(.*\))?:\s*$`), 3},
+	{"python", regexp.MustCompile(`(?m)^(import \w+|from [\w.]+ import \w+)|^if __name__ == |\bprint\(|\bself\.\w+|^\s*elif .*:\s*$`), 1},
+
+	{"ruby", regexp.MustCompile(`(?m)\A#!.*\bruby`), 5},
+	{"ruby", regexp.MustCompile(`(?m)^\s*def \w+[?!]?(\(.*\))?\s*$|^\s*require ['"]|^\s*puts |\.each do \|`), 2},
+	{"ruby", regexp.MustCompile(`(?m)^\s*end\s*$`), 1},
+
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+(<.*>)?\(|\bprintln!\(|\blet mut \w+|^use \w+::`), 3},
+	{"rust", regexp.MustCompile(`(?m)\bimpl\b|&mut |::new\(|\bmatch \w+ \{`), 1},
+
+	{"sql", regexp.MustCompile(`(?mi)^\s*(select .+ from|insert into|update \w+ set|delete from|create (table|index|view)|alter table|drop table)\b`), 3},
+	{"sql", regexp.MustCompile(`(?mi)\b(where|join|group by|order by|values|primary key)\b`), 1},
+
+	{"swift", regexp.MustCompile(`(?m)^import (Foundation|UIKit|SwiftUI)\b|\bguard let \w+|\bfunc \w+\(.*\) -> \w+`), 4},
+	{"swift", regexp.MustCompile(`(?m)^\s*(let|var) \w+(: \w+)? = |^\s*func \w+\(`), 1},
+
+	{"toml", regexp.MustCompile(`(?m)^\[\[?[\w.-]+\]\]?\s*$`), 2},
+	{"toml", regexp.MustCompile(`(?m)^[\w.-]+ = ("|'|\d|\[|\{|true|false)`), 2},
+
+	{"typescript", regexp.MustCompile(`(?m)^\s*(export )?(interface|type) \w+(<.*>)? (=|\{)|: (string|number|boolean|void|any)\b`), 3},
+
+	{"yaml", regexp.MustCompile(`(?m)\A---\s*$`), 2},
+	{"yaml", regexp.MustCompile(`(?m)^\s*[\w-]+:( [^{;]*)?$`), 1},
+	{"yaml", regexp.MustCompile(`(?m)^\s*- [\w-]+:`), 2},
+	{"yaml", regexp.MustCompile(`(?m)^[\w-]+:\s*\n\s+[\w-]+:`), 2},
+}
+
+// Detect() guesses the language of the content from clues in it, returning
+// PlainText if none of the Languages is a convincing match. It is a heuristic,
+// and so gets it wrong sometimes; authors can always pick the language instead.
+func Detect(content string) string {
+	if len(content) > detectBytes {
+		content = content[:detectBytes]
+	}
+
+	// Content which parses as a JSON object or array can't be anything else.
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if json.Valid([]byte(trimmed)) {
+			return "json"
+		}
+	}
+
+	scores := map[string]int{}
+	for _, c := range clues {
+		if c.pattern.MatchString(content) {
+			scores[c.language] += c.weight
+		}
+	}
+
+	// Ties go to the language listed first, so that the result doesn't depend
+	// on the order the map is iterated in.
+	best, bestScore := PlainText, minScore-1
+	for _, l := range Languages {
+		if scores[l.Name] > bestScore {
+			best, bestScore = l.Name, scores[l.Name]
+		}
+	}
+
+	return best
+}
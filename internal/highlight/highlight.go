@@ -0,0 +1,140 @@
+// Package highlight renders snippet content as syntax-highlighted HTML. Tokens
+// are wrapped in spans with short class names (like "k" for a keyword), which
+// are coloured by main.css rather than by inline styles, so that the
+// Content-Security-Policy doesn't need to allow them.
+package highlight
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Auto is the language of a snippet whose language is detected from its
+// content each time it is shown, and PlainText that of one which isn't
+// highlighted at all.
+const (
+	Auto      = "auto"
+	PlainText = "plaintext"
+)
+
+// Language is one of the languages a snippet can be highlighted as. Name is
+// what is stored with the snippet, and is also the name of the lexer. Extension
+// is the usual file name extension of the language, for downloads.
+type Language struct {
+	Name      string
+	Label     string
+	Extension string
+}
+
+// Languages lists the languages offered on the snippet forms.
+var Languages = []Language{
+	{Auto, "Auto-detect", ""},
+	{PlainText, "Plain text", ".txt"},
+	{"bash", "Bash", ".sh"},
+	{"c", "C", ".c"},
+	{"cpp", "C++", ".cpp"},
+	{"csharp", "C#", ".cs"},
+	{"css", "CSS", ".css"},
+	{"diff", "Diff", ".diff"},
+	{"dockerfile", "Dockerfile", ".dockerfile"},
+	{"go", "Go", ".go"},
+	{"html", "HTML", ".html"},
+	{"java", "Java", ".java"},
+	{"javascript", "JavaScript", ".js"},
+	{"json", "JSON", ".json"},
+	{"kotlin", "Kotlin", ".kt"},
+	{"markdown", "Markdown", ".md"},
+	{"php", "PHP", ".php"},
+	{"python", "Python", ".py"},
+	{"ruby", "Ruby", ".rb"},
+	{"rust", "Rust", ".rs"},
+	{"sql", "SQL", ".sql"},
+	{"swift", "Swift", ".swift"},
+	{"toml", "TOML", ".toml"},
+	{"typescript", "TypeScript", ".ts"},
+	{"yaml", "YAML", ".yaml"},
+}
+
+// style is the colour scheme of the highlighted code. The colours in main.css
+// were generated from it by the WriteCSS() method of the formatter.
+var style = styles.Get("github")
+
+// Known() returns true if name is the name of one of the Languages.
+func Known(name string) bool {
+	for _, l := range Languages {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Label() returns the human-readable name of a language, or "Plain text" for a
+// name which isn't one of the Languages.
+func Label(name string) string {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l.Label
+		}
+	}
+	return "Plain text"
+}
+
+// Extension() returns the file name extension of a language, or ".txt" for a
+// name which isn't one of the Languages. Auto must be resolved first.
+func Extension(name string) string {
+	for _, l := range Languages {
+		if l.Name == name && l.Extension != "" {
+			return l.Extension
+		}
+	}
+	return ".txt"
+}
+
+// Resolve() returns the language to highlight the content as: the detected one
+// if language is Auto, otherwise language itself.
+func Resolve(content, language string) string {
+	if language == Auto {
+		return Detect(content)
+	}
+	return language
+}
+
+// HTML() returns the content highlighted as the given language, with line
+// numbers, wrapped in <pre class="chroma"><code>. The content is HTML-escaped, so the result is
+// safe to render as is. A language without a lexer is treated as plain text.
+//
+// Each line number links to the line's own id, which is idPrefix followed by
+// L1, L2 and so on, so that the lines of several files on one page can be told
+// apart.
+func HTML(content, language, idPrefix string) (template.HTML, error) {
+	lexer := lexers.Get(Resolve(content, language))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	// Merge runs of tokens of the same type, which keeps the number of spans
+	// down without changing how the result looks.
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	// Use classes instead of styles, which the Content-Security-Policy would
+	// block.
+	formatter := html.New(html.WithClasses(true), html.WithLineNumbers(true), html.WithLinkableLineNumbers(true, idPrefix+"L"))
+
+	var b strings.Builder
+
+	err = formatter.Format(&b, style, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(b.String()), nil
+}
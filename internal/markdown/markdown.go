@@ -0,0 +1,178 @@
+// Package markdown renders snippets written in Markdown as HTML, and lists their
+// headings for a table of contents.
+//
+// The output is marked as safe for html/template, so it goes through two lines
+// of defence: the renderer drops any raw HTML in the source and refuses
+// dangerous link schemes like javascript:, and the result is then run through
+// an allowlist sanitizer, which only keeps the elements and attributes that
+// Markdown itself produces.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// MaxTOCLevel is the deepest level of heading listed in the table of contents.
+const MaxTOCLevel = 3
+
+// idPrefix starts the id of every heading, so that they can't clash with (or
+// clobber) the ids of the page the snippet is shown in.
+const idPrefix = "md-"
+
+// Heading is a heading in a Markdown document. ID is the id of the rendered
+// heading element, to link to from the table of contents.
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// Document is a rendered Markdown document. Headings holds its headings down to
+// MaxTOCLevel, in the order they appear.
+type Document struct {
+	HTML     template.HTML
+	Headings []Heading
+}
+
+// renderer converts GitHub Flavored Markdown (tables, strikethrough, autolinks
+// and task lists) to HTML. Without the goldmark.WithUnsafe() option it leaves
+// out raw HTML and dangerous links.
+var renderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// policy is the sanitizer's allowlist: bluemonday's policy for user-generated
+// content, plus the heading ids and the disabled checkboxes of task lists.
+// That policy already lets through simple ids on any element, so the sanitizer
+// doesn't enforce idPrefix; it holds because the renderer drops raw HTML and
+// makes every id itself.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^`+idPrefix+`[\p{L}\p{N}-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render() renders the Markdown source as sanitized HTML. The ids of its
+// headings start with idPrefix followed by prefix, so that several documents on
+// one page each get their own ids; prefix may only contain letters, digits and
+// hyphens.
+func Render(source, prefix string) (*Document, error) {
+	src := []byte(source)
+
+	doc := renderer.Parser().Parse(text.NewReader(src))
+
+	// The heading ids are made from the text of the headings rather than
+	// their source, which would bring the names of any inline HTML tags with
+	// it. They are only unique within a document.
+	ids := &headingIDs{prefix: idPrefix + prefix, seen: map[string]bool{}}
+	headings := []Heading{}
+
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		title := plainText(h, src)
+		id := ids.generate(title)
+		h.SetAttributeString("id", []byte(id))
+
+		if h.Level <= MaxTOCLevel {
+			headings = append(headings, Heading{Level: h.Level, ID: id, Text: title})
+		}
+
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = renderer.Renderer().Render(&buf, src, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		HTML:     template.HTML(policy.SanitizeBytes(buf.Bytes())),
+		Headings: headings,
+	}, nil
+}
+
+// plainText() returns the text of a node without any formatting, e.g. "Use
+// go test" for the heading "Use `go test`".
+func plainText(n ast.Node, src []byte) string {
+	var b strings.Builder
+
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			switch c := c.(type) {
+			case *ast.Text:
+				b.Write(c.Segment.Value(src))
+				if c.SoftLineBreak() || c.HardLineBreak() {
+					b.WriteByte(' ')
+				}
+			case *ast.String:
+				b.Write(c.Value)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.TrimSpace(b.String())
+}
+
+// headingIDs makes the ids of a document's headings from their text, e.g.
+// "md-getting-started", appending a number to repeats.
+type headingIDs struct {
+	prefix string
+	seen   map[string]bool
+}
+
+func (ids *headingIDs) generate(value string) string {
+	var b strings.Builder
+
+	// Keep the letters and digits, with a hyphen in place of each run of
+	// anything else.
+	gap := false
+	for _, r := range strings.ToLower(value) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			gap = true
+			continue
+		}
+		if gap && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		gap = false
+		b.WriteRune(r)
+	}
+
+	base := ids.prefix + b.String()
+	if b.Len() == 0 {
+		base = ids.prefix + "section"
+	}
+
+	id := base
+	for i := 1; ids.seen[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	ids.seen[id] = true
+
+	return id
+}
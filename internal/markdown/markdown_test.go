@@ -0,0 +1,228 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderIsSafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		notWant []string
+	}{
+		{
+			name:    "Script element",
+			source:  "Hi <script>alert(1)</script> there",
+			want:    "Hi",
+			notWant: []string{"<script", "alert(1)</script>"},
+		},
+		{
+			name:    "Script block",
+			source:  "<script>\nalert(1)\n</script>",
+			notWant: []string{"<script"},
+		},
+		{
+			name:    "javascript: link",
+			source:  "[click](javascript:alert(1))",
+			want:    "click",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "javascript: link in capitals",
+			source:  "[click](JavaScript:alert(1))",
+			want:    "click",
+			notWant: []string{"javascript:", "JavaScript:"},
+		},
+		{
+			name:    "javascript: image",
+			source:  "![x](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "Event handler attribute",
+			source:  `<img src="x.png" onerror="alert(1)">`,
+			notWant: []string{"onerror", "alert(1)"},
+		},
+		{
+			name:    "Event handler in a block",
+			source:  `<div onclick="alert(1)">text</div>`,
+			notWant: []string{"onclick"},
+		},
+		{
+			name:    "Raw HTML id",
+			source:  `<h2 id="main">Usage</h2>`,
+			notWant: []string{`id="main"`},
+		},
+		{
+			name:   "Safe link",
+			source: "[docs](https://example.com/docs)",
+			want:   `href="https://example.com/docs"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Render(tt.source, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(doc.HTML)
+
+			if !strings.Contains(html, tt.want) {
+				t.Errorf("got %q; want it to contain %q", html, tt.want)
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html, s) {
+					t.Errorf("got %q; want it not to contain %q", html, s)
+				}
+			}
+		})
+	}
+}
+
+// The renderer already leaves out raw HTML, so check the sanitizer on its own
+// too, as the second line of defence.
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		want    string
+		notWant []string
+	}{
+		{
+			name:    "Script element",
+			html:    `<p>Hi</p><script>alert(1)</script>`,
+			want:    "<p>Hi</p>",
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "javascript: link",
+			html:    `<a href="javascript:alert(1)">click</a>`,
+			want:    "click",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "Event handler attribute",
+			html:    `<img src="x.png" onerror="alert(1)">`,
+			notWant: []string{"onerror", "alert(1)"},
+		},
+		{
+			name:    "Style attribute",
+			html:    `<p style="position:fixed">Hi</p>`,
+			want:    "<p>Hi</p>",
+			notWant: []string{"style"},
+		},
+		{
+			name: "Heading id",
+			html: `<h2 id="md-usage">Usage</h2>`,
+			want: `<h2 id="md-usage">Usage</h2>`,
+		},
+		{
+			name: "Task list checkbox",
+			html: `<input checked="" disabled="" type="checkbox">`,
+			want: `type="checkbox"`,
+		},
+		{
+			name:    "Text input",
+			html:    `<input type="text" value="x">`,
+			notWant: []string{`type="text"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Sanitize(tt.html)
+
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("got %q; want it to contain %q", got, tt.want)
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("got %q; want it not to contain %q", got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		prefix string
+		want   []Heading
+	}{
+		{
+			name:   "Levels",
+			source: "# Title\n\n## Usage\n\n### Options\n\n#### Details",
+			want: []Heading{
+				{Level: 1, ID: "md-title", Text: "Title"},
+				{Level: 2, ID: "md-usage", Text: "Usage"},
+				{Level: 3, ID: "md-options", Text: "Options"},
+			},
+		},
+		{
+			name:   "Formatting",
+			source: "## Use `go test` *now*",
+			want:   []Heading{{Level: 2, ID: "md-use-go-test-now", Text: "Use go test now"}},
+		},
+		{
+			name:   "Inline HTML",
+			source: "## Sub <b>x</b>",
+			want:   []Heading{{Level: 2, ID: "md-sub-x", Text: "Sub x"}},
+		},
+		{
+			name:   "Repeats",
+			source: "## Notes\n\n## Notes\n\n## Notes",
+			want: []Heading{
+				{Level: 2, ID: "md-notes", Text: "Notes"},
+				{Level: 2, ID: "md-notes-1", Text: "Notes"},
+				{Level: 2, ID: "md-notes-2", Text: "Notes"},
+			},
+		},
+		{
+			name:   "Punctuation only",
+			source: "## ?!",
+			want:   []Heading{{Level: 2, ID: "md-section", Text: "?!"}},
+		},
+		{
+			name:   "Non-ASCII",
+			source: "## Café Über",
+			want:   []Heading{{Level: 2, ID: "md-café-über", Text: "Café Über"}},
+		},
+		{
+			name:   "Prefix",
+			source: "## Usage",
+			prefix: "f2-",
+			want:   []Heading{{Level: 2, ID: "md-f2-usage", Text: "Usage"}},
+		},
+		{
+			name:   "No headings",
+			source: "Just text.",
+			want:   []Heading{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Render(tt.source, tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(doc.Headings, tt.want) {
+				t.Errorf("got %+v; want %+v", doc.Headings, tt.want)
+			}
+
+			// The ids must survive the sanitizer.
+			for _, h := range tt.want {
+				if !strings.Contains(string(doc.HTML), `id="`+h.ID+`"`) {
+					t.Errorf("got %q; want a heading with id %q", doc.HTML, h.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package migrations holds the versioned database schema for each supported
+// SQL dialect, and a Migrator which applies and rolls back those migrations.
+//
+// Migrations live in a directory per dialect (mysql, postgres and sqlite) and
+// are named NNNN_description.up.sql and NNNN_description.down.sql. A file may
+// contain several statements. Statements are separated by a semicolon at the end
+// of a line, so a statement which contains semicolons of its own (like a trigger
+// body) must keep them in the middle of a line.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/sqlbind"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// ErrNoMigrations is returned by Down() when there is nothing to roll back.
+var ErrNoMigrations = errors.New("migrations: no applied migrations")
+
+// Migration is a single schema change, with the SQL to apply and undo it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied to the database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the migrations for one dialect to a database. The Dialect
+// must be one of "mysql", "postgres" or "sqlite".
+type Migrator struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+var fileRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrations() returns all the migrations for the Migrator's dialect, sorted
+// by version.
+func (m *Migrator) Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, m.Dialect)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: unsupported dialect %q", m.Dialect)
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		matches := fileRX.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("migrations: unexpected file %s/%s", m.Dialect, entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+
+		body, err := fs.ReadFile(files, m.Dialect+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = mg
+		}
+
+		if matches[3] == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("migrations: version %d of %s is missing its up or down file", mg.Version, m.Dialect)
+		}
+		migrations = append(migrations, *mg)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status() returns every known migration along with whether it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, mg := range migrations {
+		at, ok := applied[mg.Version]
+		statuses[i] = Status{Migration: mg, Applied: ok, AppliedAt: at}
+	}
+
+	return statuses, nil
+}
+
+// Pending() returns the migrations which haven't been applied yet, in the order
+// they need to be applied.
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up() applies all pending migrations and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, mg := range pending {
+		err := m.run(mg.Up, `INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`,
+			mg.Version, mg.Name, time.Now().UTC())
+		if err != nil {
+			return pending[:i], fmt.Errorf("migrations: applying %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Down() rolls back the most recently applied migration and returns it.
+func (m *Migrator) Down() (*Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if !statuses[i].Applied {
+			continue
+		}
+
+		mg := statuses[i].Migration
+
+		err := m.run(mg.Down, `DELETE FROM schema_migrations WHERE version = ?`, mg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: rolling back %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+
+		return &mg, nil
+	}
+
+	return nil, ErrNoMigrations
+}
+
+// run() executes the statements in body followed by the bookkeeping statement
+// which records the change in the schema_migrations table, inside a single
+// transaction. Note that MySQL implicitly commits after DDL statements, so there
+// a failed migration may leave some of its statements applied.
+func (m *Migrator) run(body string, record string, args ...any) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(body) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(sqlbind.Rebind(m.Dialect, record), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applied() returns the versions recorded in the schema_migrations table, along
+// with when they were applied. The table is created if it doesn't exist yet.
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	_, err := m.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`SELECT version, applied FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+var statementEndRX = regexp.MustCompile(`;[ \t]*(\r?\n|$)`)
+
+// splitStatements() splits the contents of a migration file into individual
+// statements, so that drivers which only accept one statement per Exec() call
+// (like the MySQL driver without multiStatements) can run them.
+func splitStatements(body string) []string {
+	stmts := []string{}
+
+	for _, stmt := range statementEndRX.Split(body, -1) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	return stmts
+}
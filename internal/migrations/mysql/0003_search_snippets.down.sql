@@ -0,0 +1 @@
+ALTER TABLE snippets DROP INDEX idx_snippets_fulltext;
@@ -0,0 +1,6 @@
+CREATE TABLE sessions (
+    token CHAR(43) NOT NULL PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry BIGINT NOT NULL,
+    INDEX idx_sessions_expiry (expiry)
+);
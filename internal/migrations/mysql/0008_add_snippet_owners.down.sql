@@ -0,0 +1,5 @@
+ALTER TABLE snippets
+    DROP FOREIGN KEY fk_snippets_owner,
+    DROP INDEX idx_snippets_owner_id,
+    DROP COLUMN owner_id;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE snippets
+    ADD COLUMN owner_id INTEGER NULL,
+    ADD INDEX idx_snippets_owner_id (owner_id),
+    ADD CONSTRAINT fk_snippets_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE snippets
+    DROP INDEX snippets_uc_slug,
+    DROP COLUMN slug,
+    DROP COLUMN visibility;
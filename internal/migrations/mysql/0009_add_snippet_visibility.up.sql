@@ -0,0 +1,4 @@
+ALTER TABLE snippets
+    ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    ADD COLUMN slug CHAR(22) NULL,
+    ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
@@ -0,0 +1 @@
+ALTER TABLE snippet_files DROP INDEX idx_snippet_files_fulltext;
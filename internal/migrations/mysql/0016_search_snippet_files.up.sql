@@ -0,0 +1 @@
+ALTER TABLE snippet_files ADD FULLTEXT INDEX idx_snippet_files_fulltext (content);
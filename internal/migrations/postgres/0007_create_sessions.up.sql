@@ -0,0 +1,6 @@
+CREATE TABLE sessions (
+    token CHAR(43) PRIMARY KEY,
+    data BYTEA NOT NULL,
+    expiry BIGINT NOT NULL
+);
+CREATE INDEX idx_sessions_expiry ON sessions (expiry);
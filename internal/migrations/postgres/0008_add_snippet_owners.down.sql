@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP COLUMN owner_id;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP COLUMN slug;
+ALTER TABLE snippets DROP COLUMN visibility;
@@ -0,0 +1,2 @@
+ALTER TABLE snippets ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'public';
+ALTER TABLE snippets ADD COLUMN slug CHAR(22) CONSTRAINT snippets_uc_slug UNIQUE;
@@ -0,0 +1,2 @@
+DROP INDEX idx_snippet_files_search;
+ALTER TABLE snippet_files DROP COLUMN search;
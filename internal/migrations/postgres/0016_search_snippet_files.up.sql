@@ -0,0 +1,4 @@
+ALTER TABLE snippet_files ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', content), 'B')
+) STORED;
+CREATE INDEX idx_snippet_files_search ON snippet_files USING GIN (search);
@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_owner_id;
+ALTER TABLE snippets DROP COLUMN owner_id;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE snippets ADD COLUMN owner_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX idx_snippets_owner_id ON snippets (owner_id);
@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN hashed_passphrase CHAR(60);
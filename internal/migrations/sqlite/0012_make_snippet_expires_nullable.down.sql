@@ -0,0 +1,6 @@
+DROP INDEX idx_snippets_expires;
+ALTER TABLE snippets RENAME COLUMN expires TO old_expires;
+ALTER TABLE snippets ADD COLUMN expires DATETIME NOT NULL DEFAULT '9999-12-31 23:59:59';
+UPDATE snippets SET expires = old_expires WHERE old_expires IS NOT NULL;
+ALTER TABLE snippets DROP COLUMN old_expires;
+CREATE INDEX idx_snippets_expires ON snippets(expires);
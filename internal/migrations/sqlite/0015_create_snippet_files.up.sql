@@ -0,0 +1,9 @@
+ALTER TABLE snippets ADD COLUMN filename VARCHAR(100) NOT NULL DEFAULT '';
+CREATE TABLE snippet_files (
+    snippet_id INTEGER NOT NULL REFERENCES snippets (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    filename VARCHAR(100) NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (snippet_id, position)
+);
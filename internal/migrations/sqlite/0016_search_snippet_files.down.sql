@@ -0,0 +1,3 @@
+DROP TRIGGER snippet_files_fts_delete;
+DROP TRIGGER snippet_files_fts_insert;
+DROP TABLE snippet_files_fts;
@@ -0,0 +1,4 @@
+CREATE VIRTUAL TABLE snippet_files_fts USING fts5(snippet_id UNINDEXED, position UNINDEXED, content);
+INSERT INTO snippet_files_fts (snippet_id, position, content) SELECT snippet_id, position, content FROM snippet_files;
+CREATE TRIGGER snippet_files_fts_insert AFTER INSERT ON snippet_files BEGIN INSERT INTO snippet_files_fts (snippet_id, position, content) VALUES (new.snippet_id, new.position, new.content); END;
+CREATE TRIGGER snippet_files_fts_delete AFTER DELETE ON snippet_files BEGIN DELETE FROM snippet_files_fts WHERE snippet_id = old.snippet_id AND position = old.position; END;
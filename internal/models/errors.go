@@ -4,4 +4,14 @@ import (
 	"errors"
 )
 
-var ErrNoRecord = errors.New("models: no matching record found")
+var (
+	ErrNoRecord = errors.New("models: no matching record found")
+
+	// ErrInvalidCredentials is returned when a user tries to log in with an
+	// email address or password which is wrong.
+	ErrInvalidCredentials = errors.New("models: invalid credentials")
+
+	// ErrDuplicateEmail is returned when a user tries to sign up with an email
+	// address which is already in use.
+	ErrDuplicateEmail = errors.New("models: duplicate email")
+)
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// File is one of the named files in a snippet, numbered from 1 in the order
+// they were added. Name is empty for the only file of a snippet created
+// without one.
+//
+// The first file is stored in the snippets row itself, as the snippet's
+// content, language and filename, so a snippet's Content and Language are
+// always those of its first file. Any others are stored in the snippet_files
+// table. Only the first file is edited by Update() and recorded in revisions.
+type File struct {
+	Number   int
+	Name     string
+	Language string
+	Content  string
+}
+
+// insertFiles() adds all but the first of the files to the snippet with the
+// given id, as part of the transaction tx.
+func (m *SnippetModel) insertFiles(tx *sql.Tx, snippetID int, files []File) error {
+	stmt := `INSERT INTO snippet_files (snippet_id, position, filename, language, content) VALUES (?, ?, ?, ?, ?)`
+
+	for i := 1; i < len(files); i++ {
+		_, err := tx.Exec(stmt, snippetID, i+1, files[i].Name, files[i].Language, files[i].Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// files() returns the snippet's files after the first, in order, using q.
+func (m *SnippetModel) files(q queryer, snippetID int) ([]File, error) {
+	stmt := `SELECT position, filename, language, content FROM snippet_files
+	WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := q.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFiles(rows)
+}
+
+// scanFiles() reads all the rows of a result set whose columns are position,
+// filename, language and content into a slice of files.
+func scanFiles(rows *sql.Rows) ([]File, error) {
+	files := []File{}
+
+	for rows.Next() {
+		var f File
+
+		err := rows.Scan(&f.Number, &f.Name, &f.Language, &f.Content)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// withFirstFile() returns the files of a snippet whose first file is held in
+// its own fields, with that file at the front of the rest.
+func withFirstFile(s *Snippet, filename string, rest []File) []File {
+	first := File{Number: 1, Name: filename, Language: s.Language, Content: s.Content}
+	return append([]File{first}, rest...)
+}
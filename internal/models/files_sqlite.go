@@ -0,0 +1,34 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// insertFiles() adds all but the first of the files to the snippet with the
+// given id, as part of the transaction tx.
+func (m *SQLiteSnippetModel) insertFiles(tx *sql.Tx, snippetID int, files []File) error {
+	stmt := `INSERT INTO snippet_files (snippet_id, position, filename, language, content) VALUES (?, ?, ?, ?, ?)`
+
+	for i := 1; i < len(files); i++ {
+		_, err := tx.Exec(stmt, snippetID, i+1, files[i].Name, files[i].Language, files[i].Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// files() returns the snippet's files after the first, in order, using q.
+func (m *SQLiteSnippetModel) files(q queryer, snippetID int) ([]File, error) {
+	stmt := `SELECT position, filename, language, content FROM snippet_files
+	WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := q.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFiles(rows)
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is an immutable copy of a snippet's title and content as they were
+// after it was created (revision 1) or after one of its edits.
+type Revision struct {
+	SnippetID int
+	Number    int
+	Title     string
+	Content   string
+	Created   time.Time
+}
+
+// This will change the title, content, tags, visibility, language and format of
+// an unexpired snippet. If the title or content changed, the new version is stored as the
+// next revision.
+func (m *SnippetModel) Update(id int, title string, content string, tags []string, visibility string, language string, format string) error {
+	// Snippets from before there were slugs get one now, in case they are being
+	// made unlisted.
+	slug, err := newSlug()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the snippet's row until the transaction ends, so that concurrent
+	// edits can't both pick the same revision number.
+	stmt := `SELECT title, content FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND id = ? FOR UPDATE`
+
+	var oldTitle, oldContent string
+
+	err = tx.QueryRow(stmt, id).Scan(&oldTitle, &oldContent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	if title != oldTitle || content != oldContent {
+		stmt = `UPDATE snippets SET title = ?, content = ? WHERE id = ?`
+
+		_, err = tx.Exec(stmt, title, content, id)
+		if err != nil {
+			return err
+		}
+
+		stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+		SELECT ?, MAX(revision) + 1, ?, ?, UTC_TIMESTAMP() FROM snippet_revisions WHERE snippet_id = ?`
+
+		_, err = tx.Exec(stmt, id, title, content, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only the title and content are part of the revision history.
+	stmt = `UPDATE snippets SET visibility = ?, language = ?, format = ?, slug = COALESCE(slug, ?) WHERE id = ?`
+
+	_, err = tx.Exec(stmt, visibility, language, format, slug, id)
+	if err != nil {
+		return err
+	}
+
+	// Replace the tags wholesale.
+	_, err = tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	err = m.insertTags(tx, id, tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will return all the revisions of an unexpired snippet, newest first.
+func (m *SnippetModel) Revisions(id int) ([]*Revision, error) {
+	stmt := `SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+	FROM snippet_revisions r JOIN snippets s ON s.id = r.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND r.snippet_id = ?
+	ORDER BY r.revision DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRevisions(rows)
+}
+
+// This will return a single revision of an unexpired snippet.
+func (m *SnippetModel) Revision(id int, number int) (*Revision, error) {
+	stmt := `SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+	FROM snippet_revisions r JOIN snippets s ON s.id = r.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND r.snippet_id = ? AND r.revision = ?`
+
+	return scanRevision(m.DB.QueryRow(stmt, id, number))
+}
+
+// scanRevisions() reads a result set of revisions into a slice. Every snippet
+// has at least one revision, so an empty result means the snippet doesn't exist
+// (or has expired) and ErrNoRecord is returned.
+func scanRevisions(rows *sql.Rows) ([]*Revision, error) {
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		r := &Revision{}
+
+		err := rows.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return revisions, nil
+}
+
+// scanRevision() reads a single revision, mapping sql.ErrNoRows to ErrNoRecord.
+func scanRevision(row *sql.Row) (*Revision, error) {
+	r := &Revision{}
+
+	err := row.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+)
+
+// This will change the title, content and tags of an unexpired snippet. If the
+// title or content changed, the new version is stored as the next revision.
+func (m *MemorySnippetModel) Update(id int, title string, content string, tags []string, visibility string, language string, format string) error {
+	// Snippets from before there were slugs get one now, in case they are being
+	// made unlisted.
+	slug, err := newSlug()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.snippets[id]
+	if !ok || expired(s, time.Now()) {
+		return ErrNoRecord
+	}
+
+	// Replace the stored snippet rather than changing it in place, as copies
+	// handed out earlier share its Tags slice.
+	c := *s
+	c.Tags = sortedTags(tags)
+	c.Visibility = visibility
+	c.Language = language
+	c.Format = format
+	if c.Slug == "" {
+		c.Slug = slug
+	}
+
+	if title != s.Title || content != s.Content {
+		c.Title = title
+		c.Content = content
+
+		m.revisions[id] = append(m.revisions[id], &Revision{
+			SnippetID: id,
+			Number:    len(m.revisions[id]) + 1,
+			Title:     title,
+			Content:   content,
+			Created:   time.Now().UTC().Truncate(time.Second),
+		})
+	}
+
+	// The content and language are those of the first file, so it changes
+	// with them.
+	c.Files = append([]File{}, s.Files...)
+	c.Files[0].Language = c.Language
+	c.Files[0].Content = c.Content
+
+	m.snippets[id] = &c
+	return nil
+}
+
+// This will return all the revisions of an unexpired snippet, newest first.
+func (m *MemorySnippetModel) Revisions(id int) ([]*Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snippets[id]
+	if !ok || expired(s, time.Now()) {
+		return nil, ErrNoRecord
+	}
+
+	stored := m.revisions[id]
+	revisions := make([]*Revision, 0, len(stored))
+
+	for i := len(stored) - 1; i >= 0; i-- {
+		r := *stored[i]
+		revisions = append(revisions, &r)
+	}
+
+	return revisions, nil
+}
+
+// This will return a single revision of an unexpired snippet.
+func (m *MemorySnippetModel) Revision(id int, number int) (*Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snippets[id]
+	if !ok || expired(s, time.Now()) {
+		return nil, ErrNoRecord
+	}
+
+	stored := m.revisions[id]
+	if number < 1 || number > len(stored) {
+		return nil, ErrNoRecord
+	}
+
+	r := *stored[number-1]
+	return &r, nil
+}
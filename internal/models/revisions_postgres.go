@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// This will change the title, content and tags of an unexpired snippet. If the
+// title or content changed, the new version is stored as the next revision.
+func (m *PostgresSnippetModel) Update(id int, title string, content string, tags []string, visibility string, language string, format string) error {
+	// Snippets from before there were slugs get one now, in case they are being
+	// made unlisted.
+	slug, err := newSlug()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Take a row lock on the snippet, which serializes concurrent edits and
+	// so the revision numbers they compute.
+	stmt := `SELECT title, content FROM snippets
+	WHERE (expires IS NULL OR expires > NOW()) AND id = $1 FOR UPDATE`
+
+	var oldTitle, oldContent string
+
+	err = tx.QueryRow(stmt, id).Scan(&oldTitle, &oldContent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	if title != oldTitle || content != oldContent {
+		stmt = `UPDATE snippets SET title = $1, content = $2 WHERE id = $3`
+
+		_, err = tx.Exec(stmt, title, content, id)
+		if err != nil {
+			return err
+		}
+
+		// Parameters in a SELECT list have no type to infer, so cast them.
+		stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+		SELECT $1, MAX(revision) + 1, $2::varchar, $3::text, NOW()
+		FROM snippet_revisions WHERE snippet_id = $1`
+
+		_, err = tx.Exec(stmt, id, title, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	stmt = `UPDATE snippets SET visibility = $1, language = $2, format = $3, slug = COALESCE(slug, $4) WHERE id = $5`
+
+	_, err = tx.Exec(stmt, visibility, language, format, slug, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	err = m.insertTags(tx, id, tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will return all the revisions of an unexpired snippet, newest first.
+func (m *PostgresSnippetModel) Revisions(id int) ([]*Revision, error) {
+	stmt := `SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+	FROM snippet_revisions r JOIN snippets s ON s.id = r.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > NOW()) AND r.snippet_id = $1
+	ORDER BY r.revision DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRevisions(rows)
+}
+
+// This will return a single revision of an unexpired snippet.
+func (m *PostgresSnippetModel) Revision(id int, number int) (*Revision, error) {
+	stmt := `SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+	FROM snippet_revisions r JOIN snippets s ON s.id = r.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > NOW()) AND r.snippet_id = $1 AND r.revision = $2`
+
+	return scanRevision(m.DB.QueryRow(stmt, id, number))
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// This will change the title, content and tags of an unexpired snippet. If the
+// title or content changed, the new version is stored as the next revision.
+func (m *SQLiteSnippetModel) Update(id int, title string, content string, tags []string, visibility string, language string, format string) error {
+	// Snippets from before there were slugs get one now, in case they are being
+	// made unlisted.
+	slug, err := newSlug()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// SQLite has no SELECT ... FOR UPDATE. Instead, write to the snippet first:
+	// that takes the database write lock for the rest of the transaction, so
+	// nothing else can add a revision between here and the insert below.
+	stmt := `UPDATE snippets SET title = ?, content = ?, visibility = ?, language = ?, format = ?, slug = COALESCE(slug, ?)
+	WHERE (expires IS NULL OR expires > datetime('now')) AND id = ?`
+
+	result, err := tx.Exec(stmt, title, content, visibility, language, format, slug, id)
+	if err != nil {
+		return err
+	}
+
+	// Unlike MySQL, SQLite counts the rows matched rather than changed, so zero
+	// means there's no such snippet.
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	// Compare against the latest revision to see whether anything changed.
+	stmt = `SELECT title, content FROM snippet_revisions
+	WHERE snippet_id = ? ORDER BY revision DESC LIMIT 1`
+
+	var oldTitle, oldContent string
+
+	err = tx.QueryRow(stmt, id).Scan(&oldTitle, &oldContent)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if title != oldTitle || content != oldContent {
+		stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+		SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, datetime('now')
+		FROM snippet_revisions WHERE snippet_id = ?`
+
+		_, err = tx.Exec(stmt, id, title, content, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	err = m.insertTags(tx, id, tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will return all the revisions of an unexpired snippet, newest first.
+func (m *SQLiteSnippetModel) Revisions(id int) ([]*Revision, error) {
+	stmt := `SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+	FROM snippet_revisions r JOIN snippets s ON s.id = r.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > datetime('now')) AND r.snippet_id = ?
+	ORDER BY r.revision DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRevisions(rows)
+}
+
+// This will return a single revision of an unexpired snippet.
+func (m *SQLiteSnippetModel) Revision(id int, number int) (*Revision, error) {
+	stmt := `SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+	FROM snippet_revisions r JOIN snippets s ON s.id = r.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > datetime('now')) AND r.snippet_id = ? AND r.revision = ?`
+
+	return scanRevision(m.DB.QueryRow(stmt, id, number))
+}
@@ -1,38 +1,182 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+	"snippetbox.sangdennis.com/internal/search"
 )
 
 // Define a Snippet type to hold data for an individual snippet.
-// The fields should correspond to the fields in MySQL snippets table.
+// The fields should correspond to the fields in MySQL snippets table. Expires
+// is the zero time for a snippet which never expires. Files lists all of the
+// snippet's files, starting with the one whose Content and Language are the
+// snippet's own (see File). It is only filled in by Get(), GetBySlug() and
+// Burn().
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID               int
+	Title            string
+	Content          string
+	Created          time.Time
+	Expires          time.Time
+	Tags             []string
+	Revision         int
+	OwnerID          int
+	Visibility       string
+	Slug             string
+	Protected        bool
+	BurnAfterReading bool
+	Language         string
+	Format           string
+	Files            []File
+}
+
+// The visibility levels of a snippet. Public snippets appear in listings and
+// search results. Unlisted snippets don't, and can only be fetched by their
+// random slug (or by their owner). Private snippets are only ever shown to their
+// owner.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// The formats a snippet can be shown in. Plain text is shown as is, code is
+// syntax highlighted in the snippet's language, and Markdown is rendered.
+const (
+	FormatPlain    = "plain"
+	FormatCode     = "code"
+	FormatMarkdown = "markdown"
+)
+
+// Ref() returns how the snippet is referred to in URLs: its slug if it is
+// unlisted, otherwise its id. Visibility and Slug are only filled in by Get()
+// and GetBySlug(); snippets in listings are always public.
+func (s *Snippet) Ref() string {
+	if s.Visibility == VisibilityUnlisted && s.Slug != "" {
+		return s.Slug
+	}
+	return strconv.Itoa(s.ID)
+}
+
+// OwnedBy() returns true if the snippet belongs to the user with the given id.
+// Snippets created before there were user accounts have no owner, and so
+// belong to nobody. OwnerID is only filled in by Get().
+func (s *Snippet) OwnedBy(userID int) bool {
+	return s.OwnerID != 0 && s.OwnerID == userID
 }
 
-// Define a SnippetModel type which wraps a sql.DB connection pool.
+// Edits() returns how many times the snippet has been edited since it was
+// created. Revision is only filled in by Get().
+func (s *Snippet) Edits() int {
+	if s.Revision < 1 {
+		return 0
+	}
+	return s.Revision - 1
+}
+
+// NewSnippet holds the details of a snippet to be created with Insert(). The
+// snippet expires Lifetime after it is created, or never if Lifetime is zero.
+// Passphrase is empty if the snippet isn't protected. There must be at least one
+// file; their Number fields are ignored.
+type NewSnippet struct {
+	Title            string
+	Files            []File
+	Lifetime         time.Duration
+	Tags             []string
+	OwnerID          int
+	Visibility       string
+	Passphrase       string
+	BurnAfterReading bool
+	Format           string
+}
+
+// SnippetPage is one page of a listing of unexpired snippets, newest first.
+// Listings are paginated by keyset rather than by offset: After is the id to
+// pass as the cursor for the next (older) page, and Before the id for the
+// previous (newer) page. Either is zero when there is no such page.
+type SnippetPage struct {
+	Snippets []*Snippet
+	Limit    int
+	After    int
+	Before   int
+}
+
+// SnippetStore describes the operations the web application needs from a
+// snippet storage backend. The handlers only depend on this interface, so the
+// concrete backend can be swapped per environment (or faked out entirely).
+type SnippetStore interface {
+	Insert(snippet NewSnippet) (int, error)
+	Get(id int, viewerID int) (*Snippet, error)
+	GetBySlug(slug string, viewerID int) (*Snippet, error)
+	GetForModify(id int) (*Snippet, error)
+	CheckPassphrase(id int, passphrase string) error
+	Burn(id int) (*Snippet, error)
+	Delete(id int) error
+	Latest() ([]*Snippet, error)
+	Page(after, before, limit int) (*SnippetPage, error)
+	Search(query string, limit int) ([]*Snippet, error)
+	DeleteExpired(limit int) (int, error)
+	ByTag(tag string, limit int) ([]*Snippet, error)
+	TopTags(limit int) ([]*TagCount, error)
+	Update(id int, title string, content string, tags []string, visibility string, language string, format string) error
+	Revisions(id int) ([]*Revision, error)
+	Revision(id int, number int) (*Revision, error)
+}
+
+// Check at compile time that SnippetModel satisfies the SnippetStore interface.
+var _ SnippetStore = (*SnippetModel)(nil)
+
+// Define a SnippetModel type which wraps a sql.DB connection pool. It is the
+// MySQL implementation of SnippetStore.
 type SnippetModel struct {
 	DB *sql.DB
 }
 
-// This will insert a new snippet into the database
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+// This will insert a new snippet, along with its tags, into the database
+func (m *SnippetModel) Insert(snippet NewSnippet) (int, error) {
+	// Every snippet gets a slug, so that it can be made unlisted later on.
+	slug, err := newSlug()
+	if err != nil {
+		return 0, err
+	}
+
+	hashedPassphrase, err := hashPassphrase(snippet.Passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// The first file goes in the snippets row, and the rest in snippet_files.
+	first := snippet.Files[0]
+
+	// The snippet, its tags and its files are written in several statements, so
+	// do it in a transaction to make sure we never store a snippet with only
+	// some of them. The deferred Rollback() is a no-op once Commit() has succeeded.
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	// Write the SQL statement to be executed
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (title, content, created, expires, owner_id, visibility, slug, hashed_passphrase, burn_after_reading, language, format, filename)
+	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND), ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	// Use DB.Exec() on the embedded connection pool to execute the statement.
+	// Use Exec() on the transaction to execute the statement.
 	// The first parameter is the SQL statement, followed by fields values for
 	// placeholder parameters.
 	// This method returns a sql.Result type, which contains basic information about
 	// what happened when the statement was executed.
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	// A NULL lifetime makes DATE_ADD() return NULL, so the snippet never expires.
+	result, err := tx.Exec(stmt, snippet.Title, first.Content, nullableSeconds(snippet.Lifetime), nullableID(snippet.OwnerID),
+		snippet.Visibility, slug, hashedPassphrase, snippet.BurnAfterReading, first.Language, snippet.Format, first.Name)
 	if err != nil {
 		return 0, err
 	}
@@ -44,29 +188,96 @@ func (m *SnippetModel) Insert(title string, content string, expires int) (int, e
 		return 0, err
 	}
 
+	err = m.insertTags(tx, int(id), snippet.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.insertFiles(tx, int(id), snippet.Files)
+	if err != nil {
+		return 0, err
+	}
+
+	// Record the snippet as it was created as its first revision.
+	stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+	SELECT id, 1, title, content, created FROM snippets WHERE id = ?`
+
+	_, err = tx.Exec(stmt, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
 	// The ID returned has the type int64, so convert it to an int type before returning.
 	return int(id), nil
 }
 
-// This will fetch a specific snippet based on its id.
-func (m *SnippetModel) Get(id int) (*Snippet, error) {
+// This will fetch a specific snippet based on its id. Unlisted and private
+// snippets are only returned to their owner, whose id is passed as viewerID;
+// anybody else needs an unlisted snippet's slug to fetch it.
+func (m *SnippetModel) Get(id int, viewerID int) (*Snippet, error) {
+	return m.get(m.DB, `id = ? AND (visibility = 'public' OR owner_id = ?)`, id, viewerID)
+}
+
+// This will fetch a specific snippet based on its slug. Private snippets are
+// still only returned to their owner.
+func (m *SnippetModel) GetBySlug(slug string, viewerID int) (*Snippet, error) {
+	return m.get(m.DB, `slug = ? AND (visibility <> 'private' OR owner_id = ?)`, slug, viewerID)
+}
+
+// This will fetch a specific snippet based on its id, whatever its visibility.
+// It is only for the edit and delete pages, where admins may change anybody's
+// snippet; the caller must check that the user is allowed to.
+func (m *SnippetModel) GetForModify(id int) (*Snippet, error) {
+	return m.get(m.DB, `id = ?`, id)
+}
+
+// This will check a passphrase against the one protecting a snippet, returning
+// ErrInvalidCredentials if it is wrong. A snippet without a passphrase accepts
+// any passphrase.
+func (m *SnippetModel) CheckPassphrase(id int, passphrase string) error {
+	var hashedPassphrase []byte
+
+	stmt := `SELECT hashed_passphrase FROM snippets WHERE id = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassphrase)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	return checkPassphrase(hashedPassphrase, passphrase)
+}
+
+// get() fetches the unexpired snippet which matches the condition, using q,
+// which is either the connection pool or a transaction.
+func (m *SnippetModel) get(q queryer, condition string, args ...any) (*Snippet, error) {
 	// Write the SQL statement to be executed
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() AND id=?`
+	stmt := `SELECT id, title, content, created, expires,
+	(SELECT COALESCE(MAX(revision), 1) FROM snippet_revisions WHERE snippet_id = snippets.id),
+	COALESCE(owner_id, 0), visibility, COALESCE(slug, ''), hashed_passphrase IS NOT NULL, burn_after_reading, language, format, filename
+	FROM snippets WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND ` + condition
 
 	// Use the QueryRow() method on the connection pool to execute the SQL statement.
-	// Pass in the untrusted id variable as the value for the placeholder parameter.
+	// Pass in the untrusted values as the values for the placeholder parameters.
 	// This returns a pointer to a sql.Row object which holds the result from db.
-	row := m.DB.QueryRow(stmt, id)
+	row := q.QueryRow(stmt, args...)
 
 	// Initialize a pointer to a new zeroed Snippet struct
 	s := &Snippet{}
+	var filename string
 
 	// Use row.Scan() to copy the values from each field in sql.Row to the corresponding
 	// field in the Snippet struct. The arguments to row.Scan() are *pointers* to the place
 	// you want to copy the data into, and the no. of arguments must be exactly the same as
 	// the number of columns returned by the statement.
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires), &s.Revision, &s.OwnerID, &s.Visibility, &s.Slug, &s.Protected, &s.BurnAfterReading, &s.Language, &s.Format, &filename)
 	if err != nil {
 		// If the query returns no rows, then row.Scan() will return a sql.ErrNoRows error.
 		// Use errors.Is() to check the specific error it is, and return our own ErrNoRecord
@@ -78,6 +289,19 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 		}
 	}
 
+	// Fetch the snippet's tags with a second query.
+	s.Tags, err = m.tags(q, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// And the rest of its files with a third.
+	rest, err := m.files(q, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.Files = withFirstFile(s, filename, rest)
+
 	// If everything went OK then return the Snippet object.
 	return s, nil
 }
@@ -86,7 +310,7 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	// Write the SQL statement to be executed
 	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' ORDER BY id DESC LIMIT 10`
 
 	// Use the Query() method on the connection pool to execute the SQL statement
 	// It returns a sql.Rows resultset containing the result of our query.
@@ -115,7 +339,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 		// object that we created. Again, the arguments to row.Scan() must be pointers to
 		// the place you want to copy the data into, and the no. of arguments must be exactly
 		// same as the number of columns returned by the SQL statement.
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires))
 		if err != nil {
 			return nil, err
 		}
@@ -133,3 +357,327 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	// If everything went OK then return the Snippets slice.
 	return snippets, nil
 }
+
+// This will delete up to limit expired snippets, and return how many were deleted.
+func (m *SnippetModel) DeleteExpired(limit int) (int, error) {
+	// MySQL supports LIMIT on single-table DELETE statements, which keeps each
+	// batch (and the locks it holds) small.
+	stmt := `DELETE FROM snippets WHERE expires <= UTC_TIMESTAMP() LIMIT ?`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// This will return a page of at most limit unexpired snippets, newest first. If
+// after is non-zero the page starts at the snippet immediately older than it;
+// otherwise if before is non-zero the page ends at the snippet immediately newer
+// than it. With neither set, the page holds the newest snippets.
+func (m *SnippetModel) Page(after, before, limit int) (*SnippetPage, error) {
+	var rows *sql.Rows
+	var err error
+
+	// One extra row is fetched to find out whether there's another page beyond
+	// this one. The id index makes each of these a cheap range scan, however
+	// deep into the listing the page is.
+	switch {
+	case after > 0:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' AND id < ? ORDER BY id DESC LIMIT ?`
+		rows, err = m.DB.Query(stmt, after, limit+1)
+	case before > 0:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' AND id > ? ORDER BY id ASC LIMIT ?`
+		rows, err = m.DB.Query(stmt, before, limit+1)
+	default:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' ORDER BY id DESC LIMIT ?`
+		rows, err = m.DB.Query(stmt, limit+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSnippetPage(snippets, after, before, limit), nil
+}
+
+// This will return up to limit unexpired snippets matching the search query,
+// best matches first. Snippets protected by a passphrase or burnt after reading
+// are left out, so that their content can't be probed by searching for it.
+func (m *SnippetModel) Search(query string, limit int) ([]*Snippet, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*Snippet{}, nil
+	}
+
+	// Use a boolean mode full-text search, where terms may be a prefix of the
+	// word (*). Natural language mode ignores words which appear in more than
+	// half the rows, which makes small tables unsearchable.
+	//
+	// Every term is required (+), except those InnoDB leaves out of its index:
+	// short words and stopwords. Requiring one of those would only match rows
+	// with a longer word starting with it, so they are left optional instead,
+	// only counting towards the ranking. This makes MySQL a little more lenient
+	// than the other databases, which require every term.
+	for i := range terms {
+		if innodbIndexes(terms[i]) {
+			terms[i] = "+" + terms[i] + "*"
+		} else {
+			terms[i] = terms[i] + "*"
+		}
+	}
+	against := strings.Join(terms, " ")
+
+	// A snippet matches if its title and first file do, or if any of its other
+	// files does. Those matching only in another file are ranked last.
+	stmt := `SELECT id, title, content, created, expires FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' AND hashed_passphrase IS NULL AND NOT burn_after_reading
+	AND (MATCH(title, content) AGAINST(? IN BOOLEAN MODE)
+		OR id IN (SELECT snippet_id FROM snippet_files WHERE MATCH(content) AGAINST(? IN BOOLEAN MODE)))
+	ORDER BY MATCH(title, content) AGAINST(? IN BOOLEAN MODE) DESC, id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, against, against, against, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSnippets(rows)
+}
+
+// innodbMinTokenSize is the default innodb_ft_min_token_size: shorter words are
+// left out of InnoDB's full-text indexes.
+const innodbMinTokenSize = 3
+
+// innodbStopwords is InnoDB's default full-text stopword list, whose words are
+// left out of its full-text indexes.
+var innodbStopwords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "com": true, "de": true, "en": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"la": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "when": true,
+	"where": true, "who": true, "will": true, "with": true, "und": true,
+	"www": true,
+}
+
+// innodbIndexes() returns true if InnoDB, with its default settings, keeps the
+// lower-cased word in its full-text indexes.
+func innodbIndexes(word string) bool {
+	return utf8.RuneCountInString(word) >= innodbMinTokenSize && !innodbStopwords[word]
+}
+
+// nullTime scans a nullable DATETIME column into a time.Time, which is left as
+// the zero time for NULL. Convert a *time.Time to use it, as in
+// row.Scan((*nullTime)(&s.Expires)).
+type nullTime time.Time
+
+func (t *nullTime) Scan(value any) error {
+	var nt sql.NullTime
+
+	err := nt.Scan(value)
+	if err != nil {
+		return err
+	}
+
+	*t = nullTime(nt.Time)
+	return nil
+}
+
+// scanSnippets() reads all the rows of a result set whose columns are id, title,
+// content, created and expires into a slice of snippets.
+func scanSnippets(rows *sql.Rows) ([]*Snippet, error) {
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires))
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// newSnippetPage() builds a SnippetPage from up to limit+1 snippets fetched for
+// the given cursors. When paging forwards (or from the start) the snippets must
+// be ordered newest first; when paging backwards with before, oldest first.
+func newSnippetPage(snippets []*Snippet, after, before, limit int) *SnippetPage {
+	more := len(snippets) > limit
+	if more {
+		snippets = snippets[:limit]
+	}
+
+	page := &SnippetPage{Limit: limit}
+
+	if before > 0 && after == 0 {
+		// Put the snippets back into newest first order.
+		for i, j := 0, len(snippets)-1; i < j; i, j = i+1, j-1 {
+			snippets[i], snippets[j] = snippets[j], snippets[i]
+		}
+		page.Snippets = snippets
+
+		// We came here from an older page, so there's always one to go back to.
+		if len(snippets) > 0 {
+			page.After = snippets[len(snippets)-1].ID
+			if more {
+				page.Before = snippets[0].ID
+			}
+		}
+		return page
+	}
+
+	page.Snippets = snippets
+
+	if len(snippets) > 0 {
+		if more {
+			page.After = snippets[len(snippets)-1].ID
+		}
+		// Likewise, if we came from a newer page there's one to go back to.
+		if after > 0 {
+			page.Before = snippets[0].ID
+		}
+	}
+
+	return page
+}
+
+// This will delete a specific snippet based on its id. Its tags and revisions
+// go with it, through the ON DELETE CASCADE foreign keys.
+func (m *SnippetModel) Delete(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// This will fetch a burn-after-reading snippet and delete it, in the same
+// transaction. Only one caller ever gets the snippet: if several try at once,
+// the DELETE statements of the others wait for the first transaction to commit,
+// then find nothing left to delete and return ErrNoRecord.
+func (m *SnippetModel) Burn(id int) (*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := m.get(tx, `id = ? AND burn_after_reading`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM snippets WHERE id = ? AND burn_after_reading`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkRowsAffected(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// checkRowsAffected() returns ErrNoRecord if the DELETE or UPDATE statement which
+// produced the result didn't match any rows.
+func checkRowsAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// nullableSeconds() returns a lifetime as a whole number of seconds, rounded
+// up, with zero meaning NULL.
+func nullableSeconds(d time.Duration) any {
+	if d == 0 {
+		return nil
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// nullableID() returns the id of a row to refer to in a nullable foreign key
+// column, with zero meaning NULL.
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// queryer is the part of *sql.DB and *sql.Tx needed to run queries, so that
+// get() can be used inside and outside transactions. Inside one, as in Burn(),
+// the snippet's tags and files are read in the same transaction as its row, on
+// the transaction's connection.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// hashPassphrase() returns a bcrypt hash of the passphrase protecting a snippet,
+// or nil (stored as NULL) if there is no passphrase. bcrypt is slow on purpose,
+// to make guessing the passphrase from a leaked hash expensive.
+func hashPassphrase(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, nil
+	}
+	return bcrypt.GenerateFromPassword([]byte(passphrase), passwordCost)
+}
+
+// checkPassphrase() compares a passphrase with a snippet's bcrypt hash in the
+// same way as checkPassword(). A nil hash means there is no passphrase.
+func checkPassphrase(hashedPassphrase []byte, passphrase string) error {
+	if hashedPassphrase == nil {
+		return nil
+	}
+	return checkPassword(hashedPassphrase, passphrase)
+}
+
+// newSlug() returns a new random slug for a snippet: 16 random bytes, which is
+// far too many to guess, encoded as 22 URL-safe characters.
+func newSlug() (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,360 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/search"
+)
+
+// Check at compile time that MemorySnippetModel satisfies the SnippetStore interface.
+var _ SnippetStore = (*MemorySnippetModel)(nil)
+
+// Define a MemorySnippetModel type which keeps snippets in process memory. It
+// follows the same expiry rules as the database-backed models, but everything is
+// lost when the process exits, so it is only suitable for tests and demos. The
+// zero value is ready to use and it is safe for concurrent use.
+type MemorySnippetModel struct {
+	mu          sync.RWMutex
+	lastID      int
+	snippets    map[int]*Snippet
+	revisions   map[int][]*Revision
+	passphrases map[int][]byte
+}
+
+// This will insert a new snippet into the store
+func (m *MemorySnippetModel) Insert(snippet NewSnippet) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.snippets == nil {
+		m.snippets = make(map[int]*Snippet)
+		m.revisions = make(map[int][]*Revision)
+		m.passphrases = make(map[int][]byte)
+	}
+
+	slug, err := newSlug()
+	if err != nil {
+		return 0, err
+	}
+
+	hashedPassphrase, err := hashPassphrase(snippet.Passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// Mirror the databases, which store times in UTC with one second precision.
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// A snippet with no lifetime never expires, which is the zero Expires.
+	var expires time.Time
+	if snippet.Lifetime > 0 {
+		expires = now.Add(snippet.Lifetime).Truncate(time.Second)
+	}
+
+	// Keep a numbered copy of the files, so the caller can't change them
+	// afterwards.
+	files := make([]File, len(snippet.Files))
+	for i, f := range snippet.Files {
+		f.Number = i + 1
+		files[i] = f
+	}
+
+	m.lastID++
+	m.snippets[m.lastID] = &Snippet{
+		ID:               m.lastID,
+		Title:            snippet.Title,
+		Content:          files[0].Content,
+		Created:          now,
+		Expires:          expires,
+		Tags:             sortedTags(snippet.Tags),
+		OwnerID:          snippet.OwnerID,
+		Visibility:       snippet.Visibility,
+		Slug:             slug,
+		Protected:        hashedPassphrase != nil,
+		BurnAfterReading: snippet.BurnAfterReading,
+		Language:         files[0].Language,
+		Format:           snippet.Format,
+		Files:            files,
+	}
+	m.revisions[m.lastID] = []*Revision{
+		{SnippetID: m.lastID, Number: 1, Title: snippet.Title, Content: files[0].Content, Created: now},
+	}
+	if hashedPassphrase != nil {
+		m.passphrases[m.lastID] = hashedPassphrase
+	}
+
+	return m.lastID, nil
+}
+
+// This will fetch a specific snippet based on its id. As with the database
+// models, only the owner gets unlisted and private snippets this way.
+func (m *MemorySnippetModel) Get(id int, viewerID int) (*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snippets[id]
+	if !ok || expired(s, time.Now()) {
+		return nil, ErrNoRecord
+	}
+
+	if s.Visibility != VisibilityPublic && !s.OwnedBy(viewerID) {
+		return nil, ErrNoRecord
+	}
+
+	return m.copyWithRevision(s), nil
+}
+
+// This will fetch a specific snippet based on its slug. Private snippets are
+// still only returned to their owner.
+func (m *MemorySnippetModel) GetBySlug(slug string, viewerID int) (*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+
+	for _, s := range m.snippets {
+		if s.Slug != slug || expired(s, now) {
+			continue
+		}
+		if s.Visibility == VisibilityPrivate && !s.OwnedBy(viewerID) {
+			break
+		}
+		return m.copyWithRevision(s), nil
+	}
+
+	return nil, ErrNoRecord
+}
+
+// This will fetch a specific snippet based on its id, whatever its visibility,
+// in the same way as SnippetModel.GetForModify().
+func (m *MemorySnippetModel) GetForModify(id int) (*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snippets[id]
+	if !ok || expired(s, time.Now()) {
+		return nil, ErrNoRecord
+	}
+
+	return m.copyWithRevision(s), nil
+}
+
+// This will check a passphrase against the one protecting a snippet, in the
+// same way as SnippetModel.CheckPassphrase().
+func (m *MemorySnippetModel) CheckPassphrase(id int, passphrase string) error {
+	m.mu.RLock()
+	s, ok := m.snippets[id]
+	hashedPassphrase := m.passphrases[id]
+	m.mu.RUnlock()
+
+	if !ok || expired(s, time.Now()) {
+		return ErrNoRecord
+	}
+
+	// Compare outside the lock, since bcrypt is slow on purpose.
+	return checkPassphrase(hashedPassphrase, passphrase)
+}
+
+// copyWithRevision() returns a copy of the stored snippet with its Revision
+// filled in. The caller must hold the lock.
+func (m *MemorySnippetModel) copyWithRevision(s *Snippet) *Snippet {
+	c := copySnippet(s)
+	c.Revision = len(m.revisions[s.ID])
+	return c
+}
+
+// copySnippet() returns a copy of the stored snippet, including its tags and
+// files, so callers can't modify the stored snippet without holding the lock.
+func copySnippet(s *Snippet) *Snippet {
+	c := *s
+	c.Tags = make([]string, len(s.Tags))
+	copy(c.Tags, s.Tags)
+	c.Files = make([]File, len(s.Files))
+	copy(c.Files, s.Files)
+	return &c
+}
+
+// This will return the 10 most recently created snippets
+func (m *MemorySnippetModel) Latest() ([]*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	snippets := []*Snippet{}
+
+	for _, s := range m.snippets {
+		if listed(s, now) {
+			snippets = append(snippets, copySnippet(s))
+		}
+	}
+
+	// Newest first, which is the same as highest id first.
+	sort.Slice(snippets, func(i, j int) bool {
+		return snippets[i].ID > snippets[j].ID
+	})
+
+	if len(snippets) > 10 {
+		snippets = snippets[:10]
+	}
+
+	return snippets, nil
+}
+
+// This will return a page of at most limit unexpired snippets, newest first,
+// using the same cursors as SnippetModel.Page().
+func (m *MemorySnippetModel) Page(after, before, limit int) (*SnippetPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	snippets := []*Snippet{}
+
+	for _, s := range m.snippets {
+		if !listed(s, now) {
+			continue
+		}
+		if (after > 0 && s.ID >= after) || (after == 0 && before > 0 && s.ID <= before) {
+			continue
+		}
+		snippets = append(snippets, copySnippet(s))
+	}
+
+	// Order the snippets the same way the SQL queries do: oldest first when
+	// paging backwards, newest first otherwise.
+	backwards := after == 0 && before > 0
+	sort.Slice(snippets, func(i, j int) bool {
+		if backwards {
+			return snippets[i].ID < snippets[j].ID
+		}
+		return snippets[i].ID > snippets[j].ID
+	})
+
+	if len(snippets) > limit+1 {
+		snippets = snippets[:limit+1]
+	}
+
+	return newSnippetPage(snippets, after, before, limit), nil
+}
+
+// This will return up to limit unexpired snippets containing every term in the
+// search query, newest first.
+func (m *MemorySnippetModel) Search(query string, limit int) ([]*Snippet, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*Snippet{}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	snippets := []*Snippet{}
+
+	for _, s := range m.snippets {
+		// Leave out protected and burn-after-reading snippets, so their content
+		// can't be probed by searching for it.
+		if listed(s, now) && !s.Protected && !s.BurnAfterReading && matchesFiles(s, terms) {
+			snippets = append(snippets, copySnippet(s))
+		}
+	}
+
+	sort.Slice(snippets, func(i, j int) bool {
+		return snippets[i].ID > snippets[j].ID
+	})
+
+	if len(snippets) > limit {
+		snippets = snippets[:limit]
+	}
+
+	return snippets, nil
+}
+
+// This will delete up to limit expired snippets, and return how many were deleted.
+func (m *MemorySnippetModel) DeleteExpired(limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+
+	for id, s := range m.snippets {
+		if n >= limit {
+			break
+		}
+		if expired(s, now) {
+			delete(m.snippets, id)
+			delete(m.revisions, id)
+			delete(m.passphrases, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// This will delete a specific snippet based on its id, along with its revisions.
+func (m *MemorySnippetModel) Delete(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.snippets[id]; !ok {
+		return ErrNoRecord
+	}
+
+	delete(m.snippets, id)
+	delete(m.revisions, id)
+	delete(m.passphrases, id)
+
+	return nil
+}
+
+// This will fetch a burn-after-reading snippet and delete it. Holding the write
+// lock for both makes sure only one caller ever gets the snippet.
+func (m *MemorySnippetModel) Burn(id int) (*Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.snippets[id]
+	if !ok || !s.BurnAfterReading || expired(s, time.Now()) {
+		return nil, ErrNoRecord
+	}
+
+	c := m.copyWithRevision(s)
+
+	delete(m.snippets, id)
+	delete(m.revisions, id)
+	delete(m.passphrases, id)
+
+	return c, nil
+}
+
+// listed() returns true if the snippet belongs in listings and search results:
+// it must be public and unexpired.
+func listed(s *Snippet, now time.Time) bool {
+	return s.Visibility == VisibilityPublic && !expired(s, now)
+}
+
+// expired() returns true if the snippet has expired by now. Snippets with a zero
+// Expires never expire.
+func expired(s *Snippet, now time.Time) bool {
+	return !s.Expires.IsZero() && !s.Expires.After(now)
+}
+
+// matchesFiles() returns true if the snippet's title and first file contain all
+// the search terms, or if any one of its other files does, as in the databases'
+// full-text searches.
+func matchesFiles(s *Snippet, terms []string) bool {
+	if search.Matches(s.Title+"\n"+s.Content, terms) {
+		return true
+	}
+
+	for i := 1; i < len(s.Files); i++ {
+		if search.Matches(s.Files[i].Content, terms) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"snippetbox.sangdennis.com/internal/search"
+)
+
+// Check at compile time that PostgresSnippetModel satisfies the SnippetStore interface.
+var _ SnippetStore = (*PostgresSnippetModel)(nil)
+
+// Define a PostgresSnippetModel type which wraps a sql.DB connection pool opened
+// with the PostgreSQL driver. The created and expires columns are expected to be
+// of type TIMESTAMPTZ, with a NULL expires for snippets which never expire.
+type PostgresSnippetModel struct {
+	DB *sql.DB
+}
+
+// This will insert a new snippet, along with its tags, into the database
+func (m *PostgresSnippetModel) Insert(snippet NewSnippet) (int, error) {
+	slug, err := newSlug()
+	if err != nil {
+		return 0, err
+	}
+
+	hashedPassphrase, err := hashPassphrase(snippet.Passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// The first file goes in the snippets row, and the rest in snippet_files.
+	first := snippet.Files[0]
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// PostgreSQL uses numbered $N placeholders, and the pq driver doesn't support
+	// LastInsertId(), so ask for the new id with a RETURNING clause instead.
+	stmt := `INSERT INTO snippets (title, content, created, expires, owner_id, visibility, slug, hashed_passphrase, burn_after_reading, language, format, filename)
+	VALUES($1, $2, NOW(), NOW() + $3 * INTERVAL '1 second', $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id`
+
+	var id int
+
+	// A NULL lifetime makes the expiry time NULL, so the snippet never expires.
+	err = tx.QueryRow(stmt, snippet.Title, first.Content, nullableSeconds(snippet.Lifetime), nullableID(snippet.OwnerID),
+		snippet.Visibility, slug, hashedPassphrase, snippet.BurnAfterReading, first.Language, snippet.Format, first.Name).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.insertTags(tx, id, snippet.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.insertFiles(tx, id, snippet.Files)
+	if err != nil {
+		return 0, err
+	}
+
+	// Record the snippet as it was created as its first revision.
+	stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+	SELECT id, 1, title, content, created FROM snippets WHERE id = $1`
+
+	_, err = tx.Exec(stmt, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// This will fetch a specific snippet based on its id. Unlisted and private
+// snippets are only returned to their owner, whose id is passed as viewerID;
+// anybody else needs an unlisted snippet's slug to fetch it.
+func (m *PostgresSnippetModel) Get(id int, viewerID int) (*Snippet, error) {
+	return m.get(m.DB, `id = $1 AND (visibility = 'public' OR owner_id = $2)`, id, viewerID)
+}
+
+// This will fetch a specific snippet based on its slug. Private snippets are
+// still only returned to their owner.
+func (m *PostgresSnippetModel) GetBySlug(slug string, viewerID int) (*Snippet, error) {
+	return m.get(m.DB, `slug = $1 AND (visibility <> 'private' OR owner_id = $2)`, slug, viewerID)
+}
+
+// This will fetch a specific snippet based on its id, whatever its visibility.
+// It is only for the edit and delete pages, where admins may change anybody's
+// snippet; the caller must check that the user is allowed to.
+func (m *PostgresSnippetModel) GetForModify(id int) (*Snippet, error) {
+	return m.get(m.DB, `id = $1`, id)
+}
+
+// This will check a passphrase against the one protecting a snippet, returning
+// ErrInvalidCredentials if it is wrong. A snippet without a passphrase accepts
+// any passphrase.
+func (m *PostgresSnippetModel) CheckPassphrase(id int, passphrase string) error {
+	var hashedPassphrase []byte
+
+	stmt := `SELECT hashed_passphrase FROM snippets WHERE id = $1 AND (expires IS NULL OR expires > NOW())`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassphrase)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	return checkPassphrase(hashedPassphrase, passphrase)
+}
+
+// get() fetches the unexpired snippet which matches the condition, using q.
+func (m *PostgresSnippetModel) get(q queryer, condition string, args ...any) (*Snippet, error) {
+	stmt := `SELECT id, title, content, created, expires,
+	(SELECT COALESCE(MAX(revision), 1) FROM snippet_revisions WHERE snippet_id = snippets.id),
+	COALESCE(owner_id, 0), visibility, COALESCE(slug, ''), hashed_passphrase IS NOT NULL, burn_after_reading, language, format, filename
+	FROM snippets WHERE (expires IS NULL OR expires > NOW()) AND ` + condition
+
+	s := &Snippet{}
+	var filename string
+
+	err := q.QueryRow(stmt, args...).Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires), &s.Revision, &s.OwnerID, &s.Visibility, &s.Slug, &s.Protected, &s.BurnAfterReading, &s.Language, &s.Format, &filename)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	s.Tags, err = m.tags(q, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := m.files(q, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.Files = withFirstFile(s, filename, rest)
+
+	return s, nil
+}
+
+// This will return the 10 most recently created snippets
+func (m *PostgresSnippetModel) Latest() ([]*Snippet, error) {
+	stmt := `SELECT id, title, content, created, expires FROM snippets
+	WHERE (expires IS NULL OR expires > NOW()) AND visibility = 'public' ORDER BY id DESC LIMIT 10`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires))
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return a page of at most limit unexpired snippets, newest first,
+// using the same cursors as SnippetModel.Page().
+func (m *PostgresSnippetModel) Page(after, before, limit int) (*SnippetPage, error) {
+	var rows *sql.Rows
+	var err error
+
+	switch {
+	case after > 0:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > NOW()) AND visibility = 'public' AND id < $1 ORDER BY id DESC LIMIT $2`
+		rows, err = m.DB.Query(stmt, after, limit+1)
+	case before > 0:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > NOW()) AND visibility = 'public' AND id > $1 ORDER BY id ASC LIMIT $2`
+		rows, err = m.DB.Query(stmt, before, limit+1)
+	default:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > NOW()) AND visibility = 'public' ORDER BY id DESC LIMIT $1`
+		rows, err = m.DB.Query(stmt, limit+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSnippetPage(snippets, after, before, limit), nil
+}
+
+// This will return up to limit unexpired snippets matching the search query,
+// best matches first.
+func (m *PostgresSnippetModel) Search(query string, limit int) ([]*Snippet, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*Snippet{}, nil
+	}
+
+	// The search column is a generated tsvector of the title and content, with
+	// matches in the title weighted more heavily by ts_rank(). The snippet's
+	// other files each have a search column of their own, and a snippet
+	// matching only in one of those is ranked last.
+	stmt := `SELECT id, title, content, created, expires FROM snippets
+	WHERE (expires IS NULL OR expires > NOW()) AND visibility = 'public' AND hashed_passphrase IS NULL AND NOT burn_after_reading
+	AND (search @@ plainto_tsquery('english', $1)
+		OR id IN (SELECT f.snippet_id FROM snippet_files f WHERE f.search @@ plainto_tsquery('english', $1)))
+	ORDER BY ts_rank(search, plainto_tsquery('english', $1)) DESC, id DESC LIMIT $2`
+
+	rows, err := m.DB.Query(stmt, strings.Join(terms, " "), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSnippets(rows)
+}
+
+// This will delete up to limit expired snippets, and return how many were deleted.
+func (m *PostgresSnippetModel) DeleteExpired(limit int) (int, error) {
+	// PostgreSQL has no DELETE ... LIMIT, so select the batch of ids to delete
+	// in a subquery.
+	stmt := `DELETE FROM snippets WHERE id IN (
+		SELECT id FROM snippets WHERE expires <= NOW() LIMIT $1
+	)`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// This will delete a specific snippet based on its id, and with it its tags and
+// revisions.
+func (m *PostgresSnippetModel) Delete(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM snippets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// This will fetch a burn-after-reading snippet and delete it, in the same way
+// as SnippetModel.Burn().
+func (m *PostgresSnippetModel) Burn(id int) (*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := m.get(tx, `id = $1 AND burn_after_reading`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM snippets WHERE id = $1 AND burn_after_reading`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkRowsAffected(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
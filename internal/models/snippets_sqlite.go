@@ -0,0 +1,331 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"snippetbox.sangdennis.com/internal/search"
+)
+
+// Check at compile time that SQLiteSnippetModel satisfies the SnippetStore interface.
+var _ SnippetStore = (*SQLiteSnippetModel)(nil)
+
+// Define a SQLiteSnippetModel type which wraps a sql.DB connection pool opened
+// with the SQLite driver. It is used for local development and CI, where running
+// a MySQL server isn't practical.
+//
+// SQLite has no dedicated date/time type, so the created and expires columns are
+// stored as 'YYYY-MM-DD HH:MM:SS' UTC text, generated by the datetime() function.
+// Snippets which never expire have a NULL expires.
+// Because every value has the same format, comparing them as strings gives the
+// same result as comparing them as times.
+type SQLiteSnippetModel struct {
+	DB *sql.DB
+}
+
+// This will insert a new snippet, along with its tags, into the database
+func (m *SQLiteSnippetModel) Insert(snippet NewSnippet) (int, error) {
+	slug, err := newSlug()
+	if err != nil {
+		return 0, err
+	}
+
+	hashedPassphrase, err := hashPassphrase(snippet.Passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// The first file goes in the snippets row, and the rest in snippet_files.
+	first := snippet.Files[0]
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The '+N seconds' modifier is built by concatenating the lifetime, so it
+	// can still be passed as a placeholder parameter. A NULL lifetime makes the
+	// whole modifier NULL, and so datetime() too: the snippet never expires.
+	stmt := `INSERT INTO snippets (title, content, created, expires, owner_id, visibility, slug, hashed_passphrase, burn_after_reading, language, format, filename)
+	VALUES(?, ?, datetime('now'), datetime('now', '+' || ? || ' seconds'), ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := tx.Exec(stmt, snippet.Title, first.Content, nullableSeconds(snippet.Lifetime), nullableID(snippet.OwnerID),
+		snippet.Visibility, slug, hashedPassphrase, snippet.BurnAfterReading, first.Language, snippet.Format, first.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.insertTags(tx, int(id), snippet.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.insertFiles(tx, int(id), snippet.Files)
+	if err != nil {
+		return 0, err
+	}
+
+	// Record the snippet as it was created as its first revision.
+	stmt = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+	SELECT id, 1, title, content, created FROM snippets WHERE id = ?`
+
+	_, err = tx.Exec(stmt, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// This will fetch a specific snippet based on its id. Unlisted and private
+// snippets are only returned to their owner, whose id is passed as viewerID;
+// anybody else needs an unlisted snippet's slug to fetch it.
+func (m *SQLiteSnippetModel) Get(id int, viewerID int) (*Snippet, error) {
+	return m.get(m.DB, `id = ? AND (visibility = 'public' OR owner_id = ?)`, id, viewerID)
+}
+
+// This will fetch a specific snippet based on its slug. Private snippets are
+// still only returned to their owner.
+func (m *SQLiteSnippetModel) GetBySlug(slug string, viewerID int) (*Snippet, error) {
+	return m.get(m.DB, `slug = ? AND (visibility <> 'private' OR owner_id = ?)`, slug, viewerID)
+}
+
+// This will fetch a specific snippet based on its id, whatever its visibility.
+// It is only for the edit and delete pages, where admins may change anybody's
+// snippet; the caller must check that the user is allowed to.
+func (m *SQLiteSnippetModel) GetForModify(id int) (*Snippet, error) {
+	return m.get(m.DB, `id = ?`, id)
+}
+
+// This will check a passphrase against the one protecting a snippet, returning
+// ErrInvalidCredentials if it is wrong. A snippet without a passphrase accepts
+// any passphrase.
+func (m *SQLiteSnippetModel) CheckPassphrase(id int, passphrase string) error {
+	var hashedPassphrase []byte
+
+	stmt := `SELECT hashed_passphrase FROM snippets WHERE id = ? AND (expires IS NULL OR expires > datetime('now'))`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassphrase)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	return checkPassphrase(hashedPassphrase, passphrase)
+}
+
+// get() fetches the unexpired snippet which matches the condition, using q.
+func (m *SQLiteSnippetModel) get(q queryer, condition string, args ...any) (*Snippet, error) {
+	stmt := `SELECT id, title, content, created, expires,
+	(SELECT COALESCE(MAX(revision), 1) FROM snippet_revisions WHERE snippet_id = snippets.id),
+	COALESCE(owner_id, 0), visibility, COALESCE(slug, ''), hashed_passphrase IS NOT NULL, burn_after_reading, language, format, filename
+	FROM snippets WHERE (expires IS NULL OR expires > datetime('now')) AND ` + condition
+
+	s := &Snippet{}
+	var filename string
+
+	err := q.QueryRow(stmt, args...).Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires), &s.Revision, &s.OwnerID, &s.Visibility, &s.Slug, &s.Protected, &s.BurnAfterReading, &s.Language, &s.Format, &filename)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	s.Tags, err = m.tags(q, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := m.files(q, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.Files = withFirstFile(s, filename, rest)
+
+	return s, nil
+}
+
+// This will return the 10 most recently created snippets
+func (m *SQLiteSnippetModel) Latest() ([]*Snippet, error) {
+	stmt := `SELECT id, title, content, created, expires FROM snippets
+	WHERE (expires IS NULL OR expires > datetime('now')) AND visibility = 'public' ORDER BY id DESC LIMIT 10`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, (*nullTime)(&s.Expires))
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will return a page of at most limit unexpired snippets, newest first,
+// using the same cursors as SnippetModel.Page().
+func (m *SQLiteSnippetModel) Page(after, before, limit int) (*SnippetPage, error) {
+	var rows *sql.Rows
+	var err error
+
+	switch {
+	case after > 0:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > datetime('now')) AND visibility = 'public' AND id < ? ORDER BY id DESC LIMIT ?`
+		rows, err = m.DB.Query(stmt, after, limit+1)
+	case before > 0:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > datetime('now')) AND visibility = 'public' AND id > ? ORDER BY id ASC LIMIT ?`
+		rows, err = m.DB.Query(stmt, before, limit+1)
+	default:
+		stmt := `SELECT id, title, content, created, expires FROM snippets
+		WHERE (expires IS NULL OR expires > datetime('now')) AND visibility = 'public' ORDER BY id DESC LIMIT ?`
+		rows, err = m.DB.Query(stmt, limit+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSnippetPage(snippets, after, before, limit), nil
+}
+
+// This will return up to limit unexpired snippets matching the search query,
+// best matches first.
+func (m *SQLiteSnippetModel) Search(query string, limit int) ([]*Snippet, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*Snippet{}, nil
+	}
+
+	// Quote each term so that it can't be mistaken for FTS5 query syntax, and
+	// allow it to match as a prefix. Terms separated by spaces must all match.
+	for i := range terms {
+		terms[i] = `"` + terms[i] + `"*`
+	}
+
+	// snippets_fts is an external content FTS5 table over the snippets table,
+	// and snippet_files_fts one holding a copy of the snippet's other files,
+	// both kept up to date by triggers. A snippet matches if its title and
+	// first file do, or if any of its other files does, and is ordered by the
+	// rank of its best match.
+	stmt := `SELECT s.id, s.title, s.content, s.created, s.expires
+	FROM snippets s JOIN (
+		SELECT rowid AS id, rank FROM snippets_fts WHERE snippets_fts MATCH ?
+		UNION ALL
+		SELECT snippet_id, rank FROM snippet_files_fts WHERE snippet_files_fts MATCH ?
+	) m ON m.id = s.id
+	WHERE (s.expires IS NULL OR s.expires > datetime('now')) AND s.visibility = 'public' AND s.hashed_passphrase IS NULL AND NOT s.burn_after_reading
+	GROUP BY s.id
+	ORDER BY MIN(m.rank), s.id DESC LIMIT ?`
+
+	match := strings.Join(terms, " ")
+	rows, err := m.DB.Query(stmt, match, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSnippets(rows)
+}
+
+// This will delete up to limit expired snippets, and return how many were deleted.
+func (m *SQLiteSnippetModel) DeleteExpired(limit int) (int, error) {
+	// DELETE ... LIMIT is a compile-time option in SQLite which the driver
+	// doesn't enable, so use a subquery instead.
+	stmt := `DELETE FROM snippets WHERE id IN (
+		SELECT id FROM snippets WHERE expires <= datetime('now') LIMIT ?
+	)`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// This will delete a specific snippet based on its id. The foreign_keys pragma
+// set in the DSN makes SQLite cascade the delete to its tags and revisions, and
+// the snippets_fts_delete trigger removes it from the search index.
+func (m *SQLiteSnippetModel) Delete(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// This will fetch a burn-after-reading snippet and delete it, in the same way
+// as SnippetModel.Burn(). SQLite only lets one transaction write at a time, and
+// the DSN makes transactions take the write lock as soon as they begin, so here
+// the others wait at Begin() rather than at the DELETE.
+func (m *SQLiteSnippetModel) Burn(id int) (*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := m.get(tx, `id = ? AND burn_after_reading`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM snippets WHERE id = ? AND burn_after_reading`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkRowsAffected(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// TagCount holds a tag name and the number of unexpired snippets which have it.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// insertTags() adds the tags to the snippet with the given id, creating any tags
+// which don't exist yet, as part of the transaction tx.
+func (m *SnippetModel) insertTags(tx *sql.Tx, snippetID int, tags []string) error {
+	// If the tag already exists, the ON DUPLICATE KEY clause sets the value
+	// returned by LastInsertId() to the id of the existing row.
+	tagStmt := `INSERT INTO tags (name) VALUES (?)
+	ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`
+
+	linkStmt := `INSERT INTO snippet_tags (snippet_id, tag_id) VALUES (?, ?)`
+
+	for _, tag := range tags {
+		result, err := tx.Exec(tagStmt, tag)
+		if err != nil {
+			return err
+		}
+
+		tagID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(linkStmt, snippetID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tags() returns the names of the tags on a snippet, in alphabetical order,
+// using q.
+func (m *SnippetModel) tags(q queryer, snippetID int) ([]string, error) {
+	stmt := `SELECT t.name FROM tags t
+	JOIN snippet_tags st ON st.tag_id = t.id
+	WHERE st.snippet_id = ? ORDER BY t.name`
+
+	rows, err := q.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTags(rows)
+}
+
+// This will return up to limit of the newest unexpired snippets with the tag.
+func (m *SnippetModel) ByTag(tag string, limit int) ([]*Snippet, error) {
+	stmt := `SELECT s.id, s.title, s.content, s.created, s.expires FROM snippets s
+	JOIN snippet_tags st ON st.snippet_id = s.id
+	JOIN tags t ON t.id = st.tag_id
+	WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.visibility = 'public' AND t.name = ?
+	ORDER BY s.id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, tag, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSnippets(rows)
+}
+
+// This will return up to limit of the tags used by the most unexpired snippets,
+// most used first.
+func (m *SnippetModel) TopTags(limit int) ([]*TagCount, error) {
+	stmt := `SELECT t.name, COUNT(*) AS n FROM tags t
+	JOIN snippet_tags st ON st.tag_id = t.id
+	JOIN snippets s ON s.id = st.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.visibility = 'public'
+	GROUP BY t.id, t.name ORDER BY n DESC, t.name LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTagCounts(rows)
+}
+
+// scanTags() reads a result set with a single name column into a slice.
+func scanTags(rows *sql.Rows) ([]string, error) {
+	tags := []string{}
+
+	for rows.Next() {
+		var tag string
+
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// scanTagCounts() reads a result set with name and count columns into a slice.
+func scanTagCounts(rows *sql.Rows) ([]*TagCount, error) {
+	counts := []*TagCount{}
+
+	for rows.Next() {
+		tc := &TagCount{}
+
+		if err := rows.Scan(&tc.Name, &tc.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, tc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
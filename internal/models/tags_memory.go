@@ -0,0 +1,81 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// sortedTags() returns a sorted copy of tags, matching the order in which the
+// database-backed models return them.
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// This will return up to limit of the newest unexpired snippets with the tag.
+func (m *MemorySnippetModel) ByTag(tag string, limit int) ([]*Snippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	snippets := []*Snippet{}
+
+	for _, s := range m.snippets {
+		if !listed(s, now) {
+			continue
+		}
+		for _, t := range s.Tags {
+			if t == tag {
+				snippets = append(snippets, copySnippet(s))
+				break
+			}
+		}
+	}
+
+	sort.Slice(snippets, func(i, j int) bool {
+		return snippets[i].ID > snippets[j].ID
+	})
+
+	if len(snippets) > limit {
+		snippets = snippets[:limit]
+	}
+
+	return snippets, nil
+}
+
+// This will return up to limit of the tags used by the most unexpired snippets,
+// most used first.
+func (m *MemorySnippetModel) TopTags(limit int) ([]*TagCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	counts := map[string]int{}
+
+	for _, s := range m.snippets {
+		if listed(s, now) {
+			for _, t := range s.Tags {
+				counts[t]++
+			}
+		}
+	}
+
+	tags := []*TagCount{}
+	for name, n := range counts {
+		tags = append(tags, &TagCount{Name: name, Count: n})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// insertTags() adds the tags to the snippet with the given id, creating any tags
+// which don't exist yet, as part of the transaction tx.
+func (m *PostgresSnippetModel) insertTags(tx *sql.Tx, snippetID int, tags []string) error {
+	// DO NOTHING wouldn't return the id of an existing tag, so make the
+	// conflicting insert a no-op update instead.
+	tagStmt := `INSERT INTO tags (name) VALUES ($1)
+	ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+	RETURNING id`
+
+	linkStmt := `INSERT INTO snippet_tags (snippet_id, tag_id) VALUES ($1, $2)`
+
+	for _, tag := range tags {
+		var tagID int
+
+		err := tx.QueryRow(tagStmt, tag).Scan(&tagID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(linkStmt, snippetID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tags() returns the names of the tags on a snippet, in alphabetical order,
+// using q.
+func (m *PostgresSnippetModel) tags(q queryer, snippetID int) ([]string, error) {
+	stmt := `SELECT t.name FROM tags t
+	JOIN snippet_tags st ON st.tag_id = t.id
+	WHERE st.snippet_id = $1 ORDER BY t.name`
+
+	rows, err := q.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTags(rows)
+}
+
+// This will return up to limit of the newest unexpired snippets with the tag.
+func (m *PostgresSnippetModel) ByTag(tag string, limit int) ([]*Snippet, error) {
+	stmt := `SELECT s.id, s.title, s.content, s.created, s.expires FROM snippets s
+	JOIN snippet_tags st ON st.snippet_id = s.id
+	JOIN tags t ON t.id = st.tag_id
+	WHERE (s.expires IS NULL OR s.expires > NOW()) AND s.visibility = 'public' AND t.name = $1
+	ORDER BY s.id DESC LIMIT $2`
+
+	rows, err := m.DB.Query(stmt, tag, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSnippets(rows)
+}
+
+// This will return up to limit of the tags used by the most unexpired snippets,
+// most used first.
+func (m *PostgresSnippetModel) TopTags(limit int) ([]*TagCount, error) {
+	stmt := `SELECT t.name, COUNT(*) AS n FROM tags t
+	JOIN snippet_tags st ON st.tag_id = t.id
+	JOIN snippets s ON s.id = st.snippet_id
+	WHERE (s.expires IS NULL OR s.expires > NOW()) AND s.visibility = 'public'
+	GROUP BY t.id, t.name ORDER BY n DESC, t.name LIMIT $1`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTagCounts(rows)
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is the bcrypt work factor used to hash passwords.
+const passwordCost = 12
+
+// The roles a user can have. Admins can edit and delete anybody's snippets,
+// including private ones, which they can't otherwise see.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Define a User type. Notice how the field names and types align with the
+// columns in the users table.
+type User struct {
+	ID             int
+	Name           string
+	Email          string
+	HashedPassword []byte
+	Created        time.Time
+	Role           string
+}
+
+// IsAdmin() returns true if the user has the admin role.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// UserStore describes the operations the web application needs from a user
+// storage backend. Each snippet store has a matching user store.
+type UserStore interface {
+	Insert(name, email, password string) error
+	Authenticate(email, password string) (int, error)
+	Get(id int) (*User, error)
+	SetRole(email, role string) error
+}
+
+// Check at compile time that UserModel satisfies the UserStore interface.
+var _ UserStore = (*UserModel)(nil)
+
+// Define a UserModel type which wraps a sql.DB connection pool. It is the MySQL
+// implementation of UserStore.
+type UserModel struct {
+	DB *sql.DB
+}
+
+// This will add a new record to the users table, storing a bcrypt hash of the
+// password rather than the password itself.
+func (m *UserModel) Insert(name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO users (name, email, hashed_password, created)
+	VALUES(?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	if err != nil {
+		// If this returns an error, use errors.As() to check whether it is a
+		// MySQL error 1062 (duplicate entry) on the unique email constraint, and
+		// if so return the ErrDuplicateEmail error instead.
+		var mySQLError *mysql.MySQLError
+		if errors.As(err, &mySQLError) {
+			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
+				return ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// This will check whether a user exists with the provided email address and
+// password, and return their id if so.
+func (m *UserModel) Authenticate(email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
+
+	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	return id, checkPassword(hashedPassword, password)
+}
+
+// This will fetch a specific user based on their id. The password hash is left
+// out, since nothing which fetches a user this way needs it.
+func (m *UserModel) Get(id int) (*User, error) {
+	stmt := `SELECT id, name, email, created, role FROM users WHERE id = ?`
+
+	u := &User{}
+
+	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.Created, &u.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// This will change the role of the user with the given email address.
+func (m *UserModel) SetRole(email, role string) error {
+	result, err := m.DB.Exec(`UPDATE users SET role = ? WHERE email = ?`, role, email)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// checkPassword() compares a password with a user's bcrypt hash, returning
+// ErrInvalidCredentials if they don't match.
+func checkPassword(hashedPassword []byte, password string) error {
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	return nil
+}
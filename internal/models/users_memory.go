@@ -0,0 +1,106 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Check at compile time that MemoryUserModel satisfies the UserStore interface.
+var _ UserStore = (*MemoryUserModel)(nil)
+
+// Define a MemoryUserModel type which keeps users in process memory, to go with
+// MemorySnippetModel. The zero value is ready to use and it is safe for
+// concurrent use.
+type MemoryUserModel struct {
+	mu     sync.RWMutex
+	lastID int
+	users  map[int]*User
+}
+
+// This will add a new user to the store.
+func (m *MemoryUserModel) Insert(name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.users == nil {
+		m.users = make(map[int]*User)
+	}
+
+	if m.byEmail(email) != nil {
+		return ErrDuplicateEmail
+	}
+
+	m.lastID++
+	m.users[m.lastID] = &User{
+		ID:             m.lastID,
+		Name:           name,
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Created:        time.Now().UTC().Truncate(time.Second),
+		Role:           RoleUser,
+	}
+
+	return nil
+}
+
+// This will check whether a user exists with the provided email address and
+// password, and return their id if so.
+func (m *MemoryUserModel) Authenticate(email, password string) (int, error) {
+	m.mu.RLock()
+	u := m.byEmail(email)
+	m.mu.RUnlock()
+
+	if u == nil {
+		return 0, ErrInvalidCredentials
+	}
+
+	return u.ID, checkPassword(u.HashedPassword, password)
+}
+
+// This will fetch a specific user based on their id, without their password
+// hash.
+func (m *MemoryUserModel) Get(id int) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return nil, ErrNoRecord
+	}
+
+	c := *u
+	c.HashedPassword = nil
+	return &c, nil
+}
+
+// This will change the role of the user with the given email address.
+func (m *MemoryUserModel) SetRole(email, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := m.byEmail(email)
+	if u == nil {
+		return ErrNoRecord
+	}
+
+	u.Role = role
+	return nil
+}
+
+// byEmail() returns the user with the email address, or nil. The caller must
+// hold the lock.
+func (m *MemoryUserModel) byEmail(email string) *User {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
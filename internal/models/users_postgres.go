@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Check at compile time that PostgresUserModel satisfies the UserStore interface.
+var _ UserStore = (*PostgresUserModel)(nil)
+
+// Define a PostgresUserModel type which wraps a sql.DB connection pool opened
+// with the PostgreSQL driver.
+type PostgresUserModel struct {
+	DB *sql.DB
+}
+
+// This will add a new record to the users table.
+func (m *PostgresUserModel) Insert(name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO users (name, email, hashed_password, created)
+	VALUES($1, $2, $3, NOW())`
+
+	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	if err != nil {
+		// 23505 is the SQLSTATE code for a unique_violation.
+		var pqError *pq.Error
+		if errors.As(err, &pqError) {
+			if pqError.Code == "23505" && pqError.Constraint == "users_uc_email" {
+				return ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// This will check whether a user exists with the provided email address and
+// password, and return their id if so.
+func (m *PostgresUserModel) Authenticate(email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	stmt := "SELECT id, hashed_password FROM users WHERE email = $1"
+
+	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	return id, checkPassword(hashedPassword, password)
+}
+
+// This will fetch a specific user based on their id, without their password
+// hash.
+func (m *PostgresUserModel) Get(id int) (*User, error) {
+	stmt := `SELECT id, name, email, created, role FROM users WHERE id = $1`
+
+	u := &User{}
+
+	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.Created, &u.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// This will change the role of the user with the given email address.
+func (m *PostgresUserModel) SetRole(email, role string) error {
+	result, err := m.DB.Exec(`UPDATE users SET role = $1 WHERE email = $2`, role, email)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Check at compile time that SQLiteUserModel satisfies the UserStore interface.
+var _ UserStore = (*SQLiteUserModel)(nil)
+
+// Define a SQLiteUserModel type which wraps a sql.DB connection pool opened with
+// the SQLite driver.
+type SQLiteUserModel struct {
+	DB *sql.DB
+}
+
+// This will add a new record to the users table.
+func (m *SQLiteUserModel) Insert(name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO users (name, email, hashed_password, created)
+	VALUES(?, ?, ?, datetime('now'))`
+
+	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	if err != nil {
+		// The email column is the only unique one besides the id, so any
+		// unique constraint failure means the email address is taken.
+		var sqliteError *sqlite.Error
+		if errors.As(err, &sqliteError) && sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+
+	return nil
+}
+
+// This will check whether a user exists with the provided email address and
+// password, and return their id if so.
+func (m *SQLiteUserModel) Authenticate(email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
+
+	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	return id, checkPassword(hashedPassword, password)
+}
+
+// This will fetch a specific user based on their id, without their password
+// hash.
+func (m *SQLiteUserModel) Get(id int) (*User, error) {
+	stmt := `SELECT id, name, email, created, role FROM users WHERE id = ?`
+
+	u := &User{}
+
+	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.Created, &u.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// This will change the role of the user with the given email address.
+func (m *SQLiteUserModel) SetRole(email, role string) error {
+	result, err := m.DB.Exec(`UPDATE users SET role = ? WHERE email = ?`, role, email)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
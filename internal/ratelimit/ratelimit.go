@@ -0,0 +1,119 @@
+// Package ratelimit counts failed attempts at something, like guessing a
+// passphrase, and blocks further attempts once there have been too many.
+//
+// Attempts are counted per key (an IP address, say) in fixed windows: a key is
+// blocked once it has too many in a window, until the window ends. An attempt
+// which succeeds is refunded, so only the failures are left counted. The counts
+// are kept in memory, so they are per process and lost on restart.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts failures per key. Create one with New(). It is safe for
+// concurrent use.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// entry counts the failures for one key in its current window.
+type entry struct {
+	start    time.Time
+	failures int
+}
+
+// New() returns a Limiter which blocks a key once it has limit failures within
+// the given window.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:     limit,
+		window:    window,
+		entries:   map[string]*entry{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow() returns false if the key has used up its failures for the current
+// window, along with how long until it is allowed again.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	w, ok := l.entries[key]
+	if !ok || l.ended(w, now) || w.failures < l.limit {
+		return true, 0
+	}
+
+	return false, w.start.Add(l.window).Sub(now)
+}
+
+// Attempt() counts an attempt for the key, if it hasn't used up its failures
+// for the current window. Otherwise it returns false, along with how long until
+// the key is allowed again, and counts nothing.
+//
+// The check and the count happen together, so that concurrent attempts can't
+// all get past the check before any of them is counted. Call Refund() if the
+// attempt turns out not to be a failure.
+func (l *Limiter) Attempt(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	w, ok := l.entries[key]
+	if !ok || l.ended(w, now) {
+		w = &entry{start: now}
+		l.entries[key] = w
+	}
+
+	if w.failures >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+
+	w.failures++
+	return true, 0
+}
+
+// Refund() takes back an attempt counted by Attempt(), for one which succeeded.
+// It does nothing if the window the attempt was counted in has since ended.
+func (l *Limiter) Refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.entries[key]
+	if !ok || l.ended(w, time.Now()) || w.failures == 0 {
+		return
+	}
+
+	w.failures--
+}
+
+func (l *Limiter) ended(w *entry, now time.Time) bool {
+	return !now.Before(w.start.Add(l.window))
+}
+
+// sweep() deletes the entries whose window has ended, at most once per window
+// length, so that keys which stop failing don't use memory forever. The caller
+// must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+
+	for key, w := range l.entries {
+		if l.ended(w, now) {
+			delete(l.entries, key)
+		}
+	}
+
+	l.lastSweep = now
+}
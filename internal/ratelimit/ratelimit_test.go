@@ -0,0 +1,223 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// age() moves back the start of the key's window by d, as if d had passed.
+func age(l *Limiter, key string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if w, ok := l.entries[key]; ok {
+		w.start = w.start.Add(-d)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		aged     time.Duration
+		want     bool
+	}{
+		{name: "No failures", failures: 0, want: true},
+		{name: "Below the limit", failures: 2, want: true},
+		{name: "At the limit", failures: 3, want: false},
+		{name: "Above the limit", failures: 5, want: false},
+		{name: "Window nearly over", failures: 3, aged: 59 * time.Second, want: false},
+		{name: "Window over", failures: 3, aged: time.Minute, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(3, time.Minute)
+
+			for i := 0; i < tt.failures; i++ {
+				l.Attempt("key")
+			}
+			age(l, "key", tt.aged)
+
+			ok, wait := l.Allow("key")
+			if ok != tt.want {
+				t.Errorf("got %t; want %t", ok, tt.want)
+			}
+
+			if ok && wait != 0 {
+				t.Errorf("got a wait of %v for an allowed key; want 0", wait)
+			}
+			if !ok && (wait <= 0 || wait > time.Minute-tt.aged) {
+				t.Errorf("got a wait of %v; want no more than %v", wait, time.Minute-tt.aged)
+			}
+		})
+	}
+}
+
+func TestWindowRefills(t *testing.T) {
+	l := New(2, time.Minute)
+
+	l.Attempt("key")
+	l.Attempt("key")
+	if ok, _ := l.Allow("key"); ok {
+		t.Fatal("got allowed after using up the failures")
+	}
+
+	age(l, "key", time.Minute)
+
+	// The first attempt after the window ends starts a new one, with a full
+	// count of failures.
+	l.Attempt("key")
+	if ok, _ := l.Allow("key"); !ok {
+		t.Error("got blocked after one failure in a new window")
+	}
+
+	l.Attempt("key")
+	if ok, _ := l.Allow("key"); ok {
+		t.Error("got allowed after using up the failures of the new window")
+	}
+}
+
+func TestKeysAreSeparate(t *testing.T) {
+	l := New(2, time.Minute)
+
+	l.Attempt("a")
+	l.Attempt("a")
+	l.Attempt("b")
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "a", want: false},
+		{key: "b", want: true},
+		{key: "c", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if ok, _ := l.Allow(tt.key); ok != tt.want {
+				t.Errorf("got %t; want %t", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := New(2, time.Minute)
+
+	l.Attempt("old")
+	age(l, "old", time.Minute)
+	l.Attempt("new")
+
+	l.mu.Lock()
+	l.lastSweep = l.lastSweep.Add(-time.Minute)
+	l.mu.Unlock()
+
+	// The next attempt sweeps away the entries whose window has ended.
+	l.Attempt("new")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.entries["old"]; ok {
+		t.Error("got an entry for a key whose window has ended")
+	}
+	if _, ok := l.entries["new"]; !ok {
+		t.Error("got no entry for a key whose window hasn't ended")
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	l := New(2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Attempt("key"); !ok {
+			t.Fatalf("got attempt %d refused; want allowed", i+1)
+		}
+	}
+
+	ok, wait := l.Attempt("key")
+	if ok {
+		t.Fatal("got an attempt allowed after using up the failures")
+	}
+	if wait <= 0 || wait > time.Minute {
+		t.Errorf("got a wait of %v; want no more than %v", wait, time.Minute)
+	}
+
+	// A refused attempt isn't counted, so one refund lets the key try again.
+	l.Refund("key")
+	if ok, _ := l.Attempt("key"); !ok {
+		t.Error("got an attempt refused after a refund")
+	}
+}
+
+func TestRefund(t *testing.T) {
+	t.Run("Unknown key", func(t *testing.T) {
+		l := New(2, time.Minute)
+
+		l.Refund("key")
+		if ok, _ := l.Allow("key"); !ok {
+			t.Error("got blocked after refunding an unknown key")
+		}
+	})
+
+	t.Run("No credit", func(t *testing.T) {
+		l := New(2, time.Minute)
+
+		// Refunding more than was counted doesn't give extra attempts.
+		l.Attempt("key")
+		l.Refund("key")
+		l.Refund("key")
+		l.Attempt("key")
+		l.Attempt("key")
+		if ok, _ := l.Allow("key"); ok {
+			t.Error("got allowed after using up the failures")
+		}
+	})
+
+	t.Run("Window over", func(t *testing.T) {
+		l := New(2, time.Minute)
+
+		l.Attempt("key")
+		age(l, "key", time.Minute)
+		l.Refund("key")
+
+		l.mu.Lock()
+		failures := l.entries["key"].failures
+		l.mu.Unlock()
+		if failures != 1 {
+			t.Errorf("got %d failures; want a refund in an ended window to do nothing", failures)
+		}
+	})
+}
+
+func TestAttemptConcurrent(t *testing.T) {
+	const limit = 5
+
+	l := New(limit, time.Minute)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if ok, _ := l.Attempt("key"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != limit {
+		t.Errorf("got %d attempts allowed; want %d", allowed, limit)
+	}
+}
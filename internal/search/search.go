@@ -0,0 +1,138 @@
+// Package search contains the parts of snippet search which don't depend on
+// the storage backend: splitting a query into terms, and highlighting those
+// terms in the results.
+package search
+
+import (
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTerms is the most terms a query is split into. Any more are ignored.
+const MaxTerms = 10
+
+// Terms() splits a search query into lower-cased words, dropping punctuation and
+// duplicates. Only letters and digits are kept, so the terms are safe to use in
+// the full-text query syntax of any of the databases.
+func Terms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := []string{}
+	seen := map[string]bool{}
+
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+
+	return terms
+}
+
+// Matches() reports whether text contains every one of the terms, ignoring case.
+func Matches(text string, terms []string) bool {
+	text = strings.ToLower(text)
+
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// termsRX() returns a case-insensitive regular expression matching any of the
+// terms in query, or nil if the query has no terms. Longer terms come first, so
+// that the longest possible match is highlighted.
+func termsRX(query string) *regexp.Regexp {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		return len(terms[i]) > len(terms[j])
+	})
+
+	for i := range terms {
+		terms[i] = regexp.QuoteMeta(terms[i])
+	}
+
+	return regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// Highlight() HTML-escapes text and wraps every occurrence of the terms in query
+// in a <mark> element.
+func Highlight(text, query string) template.HTML {
+	rx := termsRX(query)
+	if rx == nil {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	var b strings.Builder
+	last := 0
+
+	for _, loc := range rx.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(b.String())
+}
+
+// Excerpt() returns roughly width characters of text around the first occurrence
+// of any of the terms in query, with an ellipsis marking any text cut off either
+// side. Line breaks are collapsed into spaces.
+func Excerpt(text, query string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+
+	start := 0
+	if rx := termsRX(query); rx != nil {
+		if loc := rx.FindStringIndex(text); loc != nil {
+			// Start a third of the way back from the match, so it has some
+			// leading context.
+			start = utf8.RuneCountInString(text[:loc[0]]) - width/3
+			if start < 0 {
+				start = 0
+			}
+		}
+	}
+
+	runes := []rune(text)
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+	}
+
+	excerpt := string(runes[start:end])
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(runes) {
+		excerpt += "…"
+	}
+
+	return excerpt
+}
@@ -0,0 +1,458 @@
+// Package session provides server-side sessions. The session data is kept in a
+// Store, and the browser only holds a signed cookie with a random token which
+// identifies it.
+//
+// Sessions have an absolute lifetime, counted from when they were created, and
+// an idle timeout, counted from the last request which used them; a session
+// ends at whichever comes first.
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cookie holds the settings for the session cookie. The cookie is always
+// HttpOnly, so it can't be read by JavaScript.
+type Cookie struct {
+	Name     string
+	Path     string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// Manager loads and saves sessions for each request, and gives handlers access
+// to the session data through the request context. Create one with New().
+type Manager struct {
+	Store       Store
+	Lifetime    time.Duration
+	IdleTimeout time.Duration
+	Cookie      Cookie
+
+	// ErrorFunc is called when a session can't be loaded or saved. By default
+	// it sends a plain 500 Internal Server Error response.
+	ErrorFunc func(http.ResponseWriter, *http.Request, error)
+
+	secret []byte
+}
+
+// New() returns a Manager which keeps sessions in store and signs its cookies
+// with secret. The lifetime, idle timeout and cookie settings start with
+// sensible defaults, which can be changed before the Manager is used.
+func New(store Store, secret []byte) *Manager {
+	return &Manager{
+		Store:       store,
+		Lifetime:    24 * time.Hour,
+		IdleTimeout: 2 * time.Hour,
+		Cookie: Cookie{
+			Name:     "session",
+			Path:     "/",
+			SameSite: http.SameSiteLaxMode,
+		},
+		ErrorFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		},
+		secret: secret,
+	}
+}
+
+type status int
+
+const (
+	unmodified status = iota
+	modified
+	destroyed
+)
+
+// sessionData is the state of one request's session. The token is empty until
+// the session is first saved.
+type sessionData struct {
+	mu       sync.Mutex
+	token    string
+	oldToken string
+	status   status
+	deadline time.Time
+	values   map[string]any
+}
+
+// record is the form in which session data is encoded for the Store.
+type record struct {
+	Deadline time.Time
+	Values   map[string]any
+}
+
+type contextKey struct{}
+
+// LoadAndSave is middleware which loads the session for the request (or starts
+// a new, empty one) and saves any changes to it once the next handler returns.
+//
+// The response is buffered, so that the session cookie can still be set after
+// the handler has finished writing.
+func (m *Manager) LoadAndSave(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on the session cookie, so caches mustn't share
+		// it between users.
+		w.Header().Add("Vary", "Cookie")
+
+		sd, err := m.load(r)
+		if err != nil {
+			m.ErrorFunc(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKey{}, sd)
+		bw := &bufferedResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(bw, r.WithContext(ctx))
+
+		err = m.save(w, sd)
+		if err != nil {
+			m.ErrorFunc(w, r, err)
+			return
+		}
+
+		if bw.code != 0 {
+			w.WriteHeader(bw.code)
+		}
+		w.Write(bw.buf.Bytes())
+	})
+}
+
+// load() returns the session named by the request's cookie, or a new session if
+// there is no valid cookie or the session has ended.
+func (m *Manager) load(r *http.Request) (*sessionData, error) {
+	sd := &sessionData{
+		deadline: time.Now().Add(m.Lifetime),
+		values:   map[string]any{},
+	}
+
+	cookie, err := r.Cookie(m.Cookie.Name)
+	if err != nil {
+		return sd, nil
+	}
+
+	token, ok := m.verify(cookie.Value)
+	if !ok {
+		return sd, nil
+	}
+
+	b, found, err := m.Store.Find(token)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return sd, nil
+	}
+
+	var rec record
+
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&rec)
+	if err != nil {
+		return nil, err
+	}
+
+	// The store should have expired the session already, but check the
+	// absolute deadline too in case its clock disagrees with ours.
+	if !time.Now().Before(rec.Deadline) {
+		return sd, nil
+	}
+
+	sd.token = token
+	sd.deadline = rec.Deadline
+	sd.values = rec.Values
+	if sd.values == nil {
+		sd.values = map[string]any{}
+	}
+
+	return sd, nil
+}
+
+// save() writes a modified session to the store and sets the session cookie. An
+// unmodified session is saved again if it has an idle timeout, to push the
+// timeout back, and a new session which was never modified isn't saved at all.
+func (m *Manager) save(w http.ResponseWriter, sd *sessionData) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	// Delete the token the session was loaded with if it has been renewed or
+	// the session destroyed.
+	if sd.oldToken != "" {
+		err := m.Store.Delete(sd.oldToken)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch sd.status {
+	case destroyed:
+		m.setCookie(w, "", time.Time{})
+		return nil
+	case unmodified:
+		if sd.token == "" || m.IdleTimeout <= 0 {
+			return nil
+		}
+	}
+
+	if sd.token == "" {
+		token, err := newToken()
+		if err != nil {
+			return err
+		}
+		sd.token = token
+	}
+
+	expiry := sd.deadline
+	if m.IdleTimeout > 0 {
+		if idle := time.Now().Add(m.IdleTimeout); idle.Before(expiry) {
+			expiry = idle
+		}
+	}
+
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(record{Deadline: sd.deadline, Values: sd.values})
+	if err != nil {
+		return err
+	}
+
+	err = m.Store.Commit(sd.token, buf.Bytes(), expiry)
+	if err != nil {
+		return err
+	}
+
+	m.setCookie(w, m.sign(sd.token), expiry)
+	return nil
+}
+
+// setCookie() sets the session cookie to value until expiry. An empty value
+// deletes the cookie.
+func (m *Manager) setCookie(w http.ResponseWriter, value string, expiry time.Time) {
+	cookie := &http.Cookie{
+		Name:     m.Cookie.Name,
+		Value:    value,
+		Path:     m.Cookie.Path,
+		Secure:   m.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: m.Cookie.SameSite,
+	}
+
+	if value == "" {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expiry.UTC()
+	}
+
+	http.SetCookie(w, cookie)
+}
+
+// sign() returns the token with an HMAC-SHA256 signature appended.
+func (m *Manager) sign(token string) string {
+	return token + "." + m.mac(token)
+}
+
+// verify() checks the signature on a cookie value made by sign(), and returns
+// the token if it is genuine.
+func (m *Manager) verify(value string) (string, bool) {
+	token, signature, found := strings.Cut(value, ".")
+	if !found {
+		return "", false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(m.mac(token))) {
+		return "", false
+	}
+
+	return token, true
+}
+
+func (m *Manager) mac(token string) string {
+	h := hmac.New(sha256.New, m.secret)
+	h.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// newToken() returns a new random session token.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// fromContext() returns the session loaded by LoadAndSave. It panics if there
+// isn't one, because that means a handler using sessions hasn't been wrapped
+// in the middleware.
+func fromContext(ctx context.Context) *sessionData {
+	sd, ok := ctx.Value(contextKey{}).(*sessionData)
+	if !ok {
+		panic("session: no session data in context")
+	}
+	return sd
+}
+
+// Get() returns the value for key in the session, or nil if there isn't one.
+func (m *Manager) Get(ctx context.Context, key string) any {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	return sd.values[key]
+}
+
+// GetString() returns the string value for key, or "" if there isn't one.
+func (m *Manager) GetString(ctx context.Context, key string) string {
+	s, _ := m.Get(ctx, key).(string)
+	return s
+}
+
+// GetInt() returns the int value for key, or 0 if there isn't one.
+func (m *Manager) GetInt(ctx context.Context, key string) int {
+	n, _ := m.Get(ctx, key).(int)
+	return n
+}
+
+// GetBool() returns the bool value for key, or false if there isn't one.
+func (m *Manager) GetBool(ctx context.Context, key string) bool {
+	b, _ := m.Get(ctx, key).(bool)
+	return b
+}
+
+// Exists() returns true if the session has a value for key.
+func (m *Manager) Exists(ctx context.Context, key string) bool {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	_, ok := sd.values[key]
+	return ok
+}
+
+// Put() sets the value for key in the session. Values of types other than the
+// basic ones must be registered with gob.Register().
+func (m *Manager) Put(ctx context.Context, key string, value any) {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.values[key] = value
+	sd.status = modified
+}
+
+// Pop() returns the value for key and removes it from the session, which suits
+// one-time values.
+func (m *Manager) Pop(ctx context.Context, key string) any {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	value, ok := sd.values[key]
+	if !ok {
+		return nil
+	}
+
+	delete(sd.values, key)
+	sd.status = modified
+
+	return value
+}
+
+// PopString() is Pop() for string values.
+func (m *Manager) PopString(ctx context.Context, key string) string {
+	s, _ := m.Pop(ctx, key).(string)
+	return s
+}
+
+// Remove() deletes the value for key from the session.
+func (m *Manager) Remove(ctx context.Context, key string) {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if _, ok := sd.values[key]; ok {
+		delete(sd.values, key)
+		sd.status = modified
+	}
+}
+
+// RenewToken() gives the session a new token, keeping its data, and deletes
+// the old token from the store. Call it whenever the user's privileges change,
+// like on login and logout, so that a token an attacker planted or learned
+// before the change is useless after it.
+func (m *Manager) RenewToken(ctx context.Context) error {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if sd.status == destroyed {
+		return errors.New("session: can't renew the token of a destroyed session")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	// Only the token first loaded from the store needs deleting; any tokens
+	// generated since were never saved.
+	if sd.oldToken == "" {
+		sd.oldToken = sd.token
+	}
+	sd.token = token
+	sd.status = modified
+
+	return nil
+}
+
+// Destroy() deletes the session from the store and the browser, and discards
+// its data. Anything put in the session afterwards starts a new session.
+func (m *Manager) Destroy(ctx context.Context) {
+	sd := fromContext(ctx)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if sd.oldToken == "" {
+		sd.oldToken = sd.token
+	}
+	sd.token = ""
+	sd.values = map[string]any{}
+	sd.status = destroyed
+}
+
+// bufferedResponseWriter holds back the response, so that headers can still be
+// added after the handler has written its body.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	buf  bytes.Buffer
+	code int
+}
+
+func (bw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return bw.buf.Write(b)
+}
+
+func (bw *bufferedResponseWriter) WriteHeader(code int) {
+	if bw.code == 0 {
+		bw.code = code
+	}
+}
@@ -0,0 +1,185 @@
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	m := New(&MemoryStore{}, []byte("secret"))
+	other := New(&MemoryStore{}, []byte("another secret"))
+
+	signed := m.sign("token")
+	token, signature, _ := strings.Cut(signed, ".")
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		ok    bool
+	}{
+		{name: "Genuine", value: signed, want: "token", ok: true},
+		{name: "Changed token", value: "tokem." + signature, ok: false},
+		{name: "Changed signature", value: token + "." + strings.ToUpper(signature), ok: false},
+		{name: "No signature", value: token, ok: false},
+		{name: "Empty signature", value: token + ".", ok: false},
+		{name: "Empty", value: "", ok: false},
+		{name: "Other secret", value: other.sign("token"), ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.verify(tt.value)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("got %q, %t; want %q, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// newTestManager() returns a Manager with a handler which puts the value of the
+// "put" query parameter in the session, if there is one, and writes back the
+// value it finds there.
+func newTestManager() (*Manager, http.Handler) {
+	m := New(&MemoryStore{}, []byte("secret"))
+
+	h := m.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.URL.Query().Get("put"); v != "" {
+			m.Put(r.Context(), "value", v)
+		}
+		w.Write([]byte(m.GetString(r.Context(), "value")))
+	}))
+
+	return m, h
+}
+
+// do() sends a request for url through h with the cookie, if it isn't nil, and
+// returns the response body and the session cookie set, if any.
+func do(t *testing.T, h http.Handler, url string, cookie *http.Cookie) (string, *http.Cookie) {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, r)
+
+	res := rr.Result()
+	for _, c := range res.Cookies() {
+		if c.Name == "session" {
+			return rr.Body.String(), c
+		}
+	}
+
+	return rr.Body.String(), nil
+}
+
+func TestLoadAndSave(t *testing.T) {
+	_, h := newTestManager()
+
+	body, cookie := do(t, h, "/", nil)
+	if body != "" || cookie != nil {
+		t.Fatalf("got %q and cookie %v for an unmodified new session; want no value and no cookie", body, cookie)
+	}
+
+	body, cookie = do(t, h, "/?put=hello", nil)
+	if body != "hello" || cookie == nil {
+		t.Fatalf("got %q and cookie %v; want %q and a cookie", body, cookie, "hello")
+	}
+	if !cookie.HttpOnly {
+		t.Error("got a cookie without HttpOnly")
+	}
+
+	body, _ = do(t, h, "/", cookie)
+	if body != "hello" {
+		t.Errorf("got %q from the saved session; want %q", body, "hello")
+	}
+}
+
+func TestTamperedCookie(t *testing.T) {
+	m, h := newTestManager()
+
+	_, cookie := do(t, h, "/?put=hello", nil)
+	token, signature, _ := strings.Cut(cookie.Value, ".")
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "Unsigned token", value: token},
+		{name: "Forged signature", value: token + "." + strings.Repeat("A", len(signature))},
+		{name: "Changed token", value: "x" + token[1:] + "." + signature},
+		{name: "Signed unknown token", value: m.sign("unknown")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := do(t, h, "/", &http.Cookie{Name: "session", Value: tt.value})
+			if body != "" {
+				t.Errorf("got %q; want an empty new session", body)
+			}
+		})
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Time
+		expiry   time.Time
+		want     string
+	}{
+		{name: "Live", deadline: time.Now().Add(time.Hour), expiry: time.Now().Add(time.Hour), want: "hello"},
+		{name: "Idle timeout passed", deadline: time.Now().Add(time.Hour), expiry: time.Now().Add(-time.Second), want: ""},
+		{name: "Lifetime passed", deadline: time.Now().Add(-time.Second), expiry: time.Now().Add(time.Hour), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, h := newTestManager()
+
+			var buf bytes.Buffer
+
+			err := gob.NewEncoder(&buf).Encode(record{Deadline: tt.deadline, Values: map[string]any{"value": "hello"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = m.Store.Commit("token", buf.Bytes(), tt.expiry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, _ := do(t, h, "/", &http.Cookie{Name: "session", Value: m.sign("token")})
+			if body != tt.want {
+				t.Errorf("got %q; want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdleTimeoutRenewed(t *testing.T) {
+	m, h := newTestManager()
+	m.IdleTimeout = time.Minute
+
+	_, cookie := do(t, h, "/?put=hello", nil)
+
+	// An unmodified session is saved again, to push its idle timeout back.
+	_, renewed := do(t, h, "/", cookie)
+	if renewed == nil {
+		t.Fatal("got no cookie for an unmodified session with an idle timeout")
+	}
+	if renewed.Value != cookie.Value {
+		t.Errorf("got a new cookie value %q; want %q", renewed.Value, cookie.Value)
+	}
+	if renewed.Expires.After(time.Now().Add(m.IdleTimeout + time.Second)) {
+		t.Errorf("got expiry %v; want no later than the idle timeout", renewed.Expires)
+	}
+}
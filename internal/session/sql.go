@@ -0,0 +1,70 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"snippetbox.sangdennis.com/internal/sqlbind"
+)
+
+// Check at compile time that SQLStore satisfies the Store interface.
+var _ Store = (*SQLStore)(nil)
+
+// SQLStore keeps sessions in the sessions table of a database. The Dialect must
+// be one of "mysql", "postgres" or "sqlite".
+//
+// The expiry column holds a Unix time in seconds rather than a date/time type,
+// because each of the databases handles those differently.
+type SQLStore struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+func (s *SQLStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+
+	stmt := `SELECT data FROM sessions WHERE token = ? AND expiry > ?`
+
+	err := s.DB.QueryRow(sqlbind.Rebind(s.Dialect, stmt), token, time.Now().Unix()).Scan(&b)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+func (s *SQLStore) Commit(token string, b []byte, expiry time.Time) error {
+	// MySQL doesn't support ON CONFLICT, but has its own form of upsert.
+	stmt := `INSERT INTO sessions (token, data, expiry) VALUES (?, ?, ?)
+	ON CONFLICT (token) DO UPDATE SET data = excluded.data, expiry = excluded.expiry`
+	if s.Dialect == "mysql" {
+		stmt = `INSERT INTO sessions (token, data, expiry) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE data = VALUES(data), expiry = VALUES(expiry)`
+	}
+
+	_, err := s.DB.Exec(sqlbind.Rebind(s.Dialect, stmt), token, b, expiry.Unix())
+	return err
+}
+
+func (s *SQLStore) Delete(token string) error {
+	_, err := s.DB.Exec(sqlbind.Rebind(s.Dialect, `DELETE FROM sessions WHERE token = ?`), token)
+	return err
+}
+
+func (s *SQLStore) DeleteExpired() (int, error) {
+	result, err := s.DB.Exec(sqlbind.Rebind(s.Dialect, `DELETE FROM sessions WHERE expiry <= ?`), time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
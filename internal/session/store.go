@@ -0,0 +1,89 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// Store is where session data is kept between requests. Implementations must
+// be safe for concurrent use.
+type Store interface {
+	// Find() returns the data for the session with the token, and whether it
+	// was found. Sessions past their expiry must not be found.
+	Find(token string) ([]byte, bool, error)
+
+	// Commit() adds or replaces the data for the session with the token, to be
+	// kept until expiry.
+	Commit(token string, b []byte, expiry time.Time) error
+
+	// Delete() removes the session with the token, if there is one.
+	Delete(token string) error
+
+	// DeleteExpired() removes all sessions past their expiry, and returns how
+	// many were removed.
+	DeleteExpired() (int, error)
+}
+
+// Check at compile time that MemoryStore satisfies the Store interface.
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore keeps sessions in process memory, so they are lost when the
+// process exits. The zero value is ready to use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]memoryItem
+}
+
+type memoryItem struct {
+	b      []byte
+	expiry time.Time
+}
+
+func (s *MemoryStore) Find(token string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.sessions[token]
+	if !ok || !time.Now().Before(item.expiry) {
+		return nil, false, nil
+	}
+
+	return item.b, true, nil
+}
+
+func (s *MemoryStore) Commit(token string, b []byte, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions == nil {
+		s.sessions = make(map[string]memoryItem)
+	}
+
+	s.sessions[token] = memoryItem{b: b, expiry: expiry}
+	return nil
+}
+
+func (s *MemoryStore) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpired() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+
+	for token, item := range s.sessions {
+		if !now.Before(item.expiry) {
+			delete(s.sessions, token)
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,33 @@
+// Package sqlbind adapts queries written with ? placeholders to the database
+// they are run against, so that the same query text can be shared between the
+// MySQL, PostgreSQL and SQLite backends.
+package sqlbind
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rebind() converts ? placeholders into the $N form that PostgreSQL expects,
+// if dialect is "postgres". Queries for any other dialect are returned as they
+// are. It doesn't look inside string literals, so the query mustn't have a ? in
+// one.
+func Rebind(dialect, query string) string {
+	if dialect != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
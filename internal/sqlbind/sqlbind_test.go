@@ -0,0 +1,26 @@
+package sqlbind
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		query   string
+		want    string
+	}{
+		{name: "PostgreSQL", dialect: "postgres", query: "SELECT a FROM t WHERE b = ? AND c > ?", want: "SELECT a FROM t WHERE b = $1 AND c > $2"},
+		{name: "PostgreSQL, no placeholders", dialect: "postgres", query: "DELETE FROM t", want: "DELETE FROM t"},
+		{name: "PostgreSQL, adjacent placeholders", dialect: "postgres", query: "VALUES (?,?)", want: "VALUES ($1,$2)"},
+		{name: "MySQL", dialect: "mysql", query: "SELECT a FROM t WHERE b = ?", want: "SELECT a FROM t WHERE b = ?"},
+		{name: "SQLite", dialect: "sqlite", query: "SELECT a FROM t WHERE b = ?", want: "SELECT a FROM t WHERE b = ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rebind(tt.dialect, tt.query); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
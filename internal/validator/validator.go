@@ -1,19 +1,42 @@
 package validator
 
 import (
+	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
+// TagRX matches a valid tag: lower-case letters and digits, optionally split
+// into words by single hyphens.
+var TagRX = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// SlugRX matches the random slug given to a snippet, which is 16 bytes
+// encoded as unpadded, URL-safe base64.
+var SlugRX = regexp.MustCompile(`^[A-Za-z0-9_-]{22}$`)
+
+// FilenameRX matches a valid name for one of a snippet's files, like "main.go",
+// "Dockerfile" or ".env": letters, digits, dots, underscores and hyphens, with
+// something other than a dot after any leading dot. That rules out "." and
+// "..", and there are no path separators, so the name is safe to use in a zip
+// archive.
+var FilenameRX = regexp.MustCompile(`^\.?[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// EmailRX is the pattern recommended by the W3C and WHATWG for checking the
+// format of an email address.
+var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
 // Define a new validation type which contains a map of validation errrors for
-// form fields.
+// form fields, and a list of errors which aren't about any one field.
 type Validator struct {
-	FieldErrors map[string]string
+	NonFieldErrors []string
+	FieldErrors    map[string]string
 }
 
-// Valid() returns true if the FieldErrors map doesn't contain any entries.
+// Valid() returns true if the FieldErrors map and NonFieldErrors slice don't
+// contain any entries.
 func (v *Validator) Valid() bool {
-	return len(v.FieldErrors) == 0
+	return len(v.FieldErrors) == 0 && len(v.NonFieldErrors) == 0
 }
 
 // AddFieldError() adds an error message to the FieldErrors map (so long as no
@@ -29,6 +52,11 @@ func (v *Validator) AddFieldError(key, message string) {
 	}
 }
 
+// AddNonFieldError() adds an error message to the NonFieldErrors slice.
+func (v *Validator) AddNonFieldError(message string) {
+	v.NonFieldErrors = append(v.NonFieldErrors, message)
+}
+
 // CheckField() adds an error message to the FieldErrors map only if a
 // validation check is not 'ok'.
 func (v *Validator) CheckField(ok bool, key, message string) {
@@ -47,6 +75,11 @@ func MaxChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) <= n
 }
 
+// MinChars() returns true if a value contains at least n characters.
+func MinChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) >= n
+}
+
 // PermittedInt() returns true if a value is in a list of permitted integers.
 func PermittedInt(value int, permittedValues ...int) bool {
 	for i := range permittedValues {
@@ -56,3 +89,35 @@ func PermittedInt(value int, permittedValues ...int) bool {
 	}
 	return false
 }
+
+// Matches() returns true if a value matches a provided compiled regular
+// expression pattern.
+func Matches(value string, rx *regexp.Regexp) bool {
+	return rx.MatchString(value)
+}
+
+// MaxItems() returns true if a list contains no more than n values.
+func MaxItems(values []string, n int) bool {
+	return len(values) <= n
+}
+
+// PermittedValue() returns true if a value is in a list of permitted strings.
+func PermittedValue(value string, permittedValues ...string) bool {
+	for i := range permittedValues {
+		if value == permittedValues[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// DurationBetween() returns true if a duration is at least min and at most max.
+func DurationBetween(d, min, max time.Duration) bool {
+	return d >= min && d <= max
+}
+
+// TimeBetween() returns true if a time is no earlier than min and no later than
+// max.
+func TimeBetween(t, min, max time.Time) bool {
+	return !t.Before(min) && !t.After(max)
+}
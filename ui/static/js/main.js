@@ -5,4 +5,163 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Copy the content of a file to the clipboard when its copy button is
+// clicked. The clipboard API is only available to pages served over HTTPS (or
+// from localhost).
+var copyButtons = document.querySelectorAll("button.copy");
+for (var i = 0; i < copyButtons.length; i++) {
+	copyButtons[i].addEventListener("click", function(e) {
+		var button = e.currentTarget;
+		var source = document.getElementById(button.dataset.source);
+		if (!navigator.clipboard) {
+			button.textContent = "Copying needs HTTPS";
+			return;
+		}
+		navigator.clipboard.writeText(source.value).then(function() {
+			button.textContent = "Copied!";
+		}, function() {
+			button.textContent = "Copy failed";
+		});
+		setTimeout(function() {
+			button.textContent = "Copy";
+		}, 2000);
+	});
+}
+
+// Highlight the lines of a snippet picked by the URL fragment, which is either
+// a single line, like #L12, or a range, like #L12-L20. The lines of the second
+// and later files of a bundle are picked in the same way, with the number of
+// the file in front, like #F2-L12. Clicking a line number picks that line, and
+// shift-clicking another in the same file then picks the range between them.
+// The raw links are kept pointing at just the lines picked.
+var codeBlocks = document.querySelectorAll(".snippet .file .chroma");
+if (codeBlocks.length > 0) {
+	var rawLinks = document.querySelectorAll(".snippet a.raw");
+	var anchor = null;
+
+	var fileNumber = function(element) {
+		return parseInt(element.closest("[data-file]").dataset.file, 10);
+	};
+
+	var linesOf = function(file) {
+		var block = document.querySelector('.snippet .file[data-file="' + file + '"] .chroma');
+		return block ? block.querySelectorAll(".line") : [];
+	};
+
+	var pickedLines = function() {
+		var match = /^#(?:F(\d+)-)?L(\d+)(?:-L(\d+))?$/.exec(window.location.hash);
+		if (!match) {
+			return null;
+		}
+		var file = match[1] ? parseInt(match[1], 10) : 1;
+		var start = parseInt(match[2], 10);
+		var end = match[3] ? parseInt(match[3], 10) : start;
+		return {file: file, start: Math.min(start, end), end: Math.max(start, end)};
+	};
+
+	var highlightLines = function() {
+		var range = pickedLines();
+		for (var i = 0; i < codeBlocks.length; i++) {
+			var file = fileNumber(codeBlocks[i]);
+			var lines = codeBlocks[i].querySelectorAll(".line");
+			for (var j = 0; j < lines.length; j++) {
+				var picked = range !== null && range.file === file && j + 1 >= range.start && j + 1 <= range.end;
+				lines[j].classList.toggle("hl", picked);
+			}
+		}
+		for (var i = 0; i < rawLinks.length; i++) {
+			var params = new URLSearchParams(rawLinks[i].search);
+			if (range && range.file === fileNumber(rawLinks[i])) {
+				params.set("lines", range.start + "-" + range.end);
+			} else {
+				params.delete("lines");
+			}
+			rawLinks[i].search = params.toString();
+		}
+		return range;
+	};
+
+	// The browser only scrolls to a single line by itself, as there's no
+	// element with the id of a range.
+	var followFragment = function() {
+		var range = highlightLines();
+		if (range) {
+			anchor = {file: range.file, line: range.start};
+			var lines = linesOf(range.file);
+			if (range.start <= lines.length) {
+				lines[range.start - 1].scrollIntoView({block: "center"});
+			}
+		}
+	};
+
+	var pickRange = function(e) {
+		var link = e.target.closest("a.lnlinks");
+		if (!link || !e.shiftKey || !anchor) {
+			return;
+		}
+		var file = fileNumber(e.currentTarget);
+		if (file !== anchor.file) {
+			return;
+		}
+		e.preventDefault();
+		var line = parseInt(link.textContent, 10);
+		var start = Math.min(anchor.line, line);
+		var end = Math.max(anchor.line, line);
+		var prefix = file > 1 ? "F" + file + "-" : "";
+		// Unlike setting location.hash, replaceState() doesn't scroll the
+		// page or fire hashchange, so the anchor line stays put.
+		history.replaceState(null, "", "#" + prefix + "L" + start + "-L" + end);
+		highlightLines();
+	};
+
+	for (var i = 0; i < codeBlocks.length; i++) {
+		codeBlocks[i].addEventListener("click", pickRange);
+	}
+
+	window.addEventListener("hashchange", followFragment);
+	followFragment();
+}
+
+// Add and remove the file entries of the create form. The fields of the
+// entries are named files[0].name, files[0].content and so on, so they are
+// renumbered after every change to keep the numbers in order.
+var fileList = document.querySelector("form .files");
+if (fileList) {
+	var entryTemplate = document.getElementById("file-entry-template");
+
+	var renumberEntries = function() {
+		var entries = fileList.querySelectorAll(".file-entry");
+		for (var i = 0; i < entries.length; i++) {
+			var fields = entries[i].querySelectorAll("[name^='files[']");
+			for (var j = 0; j < fields.length; j++) {
+				fields[j].name = fields[j].name.replace(/^files\[\d+\]/, "files[" + i + "]");
+			}
+			// There must always be at least one file.
+			entries[i].querySelector("button.remove-file").hidden = entries.length === 1;
+		}
+	};
+
+	fileList.addEventListener("click", function(e) {
+		if (e.target.matches("button.remove-file")) {
+			e.target.closest(".file-entry").remove();
+			renumberEntries();
+		} else if (e.target.matches("button.add-file")) {
+			var entry = entryTemplate.content.firstElementChild.cloneNode(true);
+			entryTemplate.before(entry);
+			renumberEntries();
+			entry.querySelector("input").focus();
+		}
+	});
+
+	renumberEntries();
+}
+
+// Send the browser's offset from UTC with the create form, so that a custom
+// expiry date and time is read as local time, and say so in the hint.
+var expiresOffset = document.querySelector('input[name="expires_offset"]');
+if (expiresOffset) {
+	expiresOffset.value = -new Date().getTimezoneOffset();
+	document.getElementById("expires-zone").textContent = "your local time";
+}